@@ -15,9 +15,29 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/attachments/{id}": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public parts of RS256/EdDSA keys that sign access tokens, identified by kid (RFC 7517). HMAC keys are never published. Other services verify tokens with these keys and must check iss and aud.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "JSON Web Key Set",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/analytics/by-building": {
             "get": {
-                "description": "Get defect attachment by attachment ID",
+                "description": "Total, open and overdue defect counts per building. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,33 +45,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defect-attachments"
+                    "analytics"
                 ],
-                "summary": "Get defect attachment",
+                "summary": "Defects by building",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Attachment ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.DefectAttachmentResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.BuildingStat"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -62,15 +144,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Delete a defect attachment by attachment ID",
+                ]
+            }
+        },
+        "/api/analytics/by-priority": {
+            "get": {
+                "description": "Defect counts grouped by priority. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -78,36 +162,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defect-attachments"
+                    "analytics"
                 ],
-                "summary": "Delete defect attachment",
+                "summary": "Defects by priority",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Attachment ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "attachment deleted successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CountItem"
                             }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -118,12 +261,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/auth/login": {
-            "post": {
-                "description": "Validate credentials and return access token with expiry seconds",
+        "/api/analytics/by-responsible": {
+            "get": {
+                "description": "Open and overdue defect counts per responsible user (unassigned defects are not included). Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -131,35 +279,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "analytics"
                 ],
-                "summary": "Login and obtain JWT",
+                "summary": "Open defects by responsible",
                 "parameters": [
                     {
-                        "description": "Login payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
-                        }
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.TokenResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ResponsibleStat"
+                            }
                         }
                     },
                     "400": {
-                        "description": "invalid request body",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "invalid credentials",
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -170,12 +378,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/auth/register": {
-            "post": {
-                "description": "Create a new user account (no JWT returned). Default role = \"engineer\".",
+        "/api/analytics/by-status": {
+            "get": {
+                "description": "Defect counts grouped by status. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -183,116 +396,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "analytics"
                 ],
-                "summary": "Register a user",
+                "summary": "Defects by status",
                 "parameters": [
                     {
-                        "description": "Registration payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.RegisterRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
-                        }
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "invalid request body or missing fields",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
                     },
-                    "409": {
-                        "description": "user already exists",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
+                    {
+                        "type": "integer",
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
-                }
-            }
-        },
-        "/api/buildings": {
-            "get": {
-                "description": "Retrieve list of all buildings",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "buildings"
                 ],
-                "summary": "List buildings",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.BuildingResponse"
+                                "$ref": "#/definitions/handlers.CountItem"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
-                    }
-                }
-            },
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Create a new building record",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "buildings"
-                ],
-                "summary": "Create building",
-                "parameters": [
-                    {
-                        "description": "Building payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CreateBuildingRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.BuildingResponse"
-                        }
                     },
-                    "400": {
-                        "description": "invalid request body or missing fields",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -303,12 +495,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/buildings/{id}": {
+        "/api/analytics/sla/by-building": {
             "get": {
-                "description": "Retrieve building by numeric id",
+                "description": "p50/p90/p95 of time to first response, time to close and time spent in each status (hours), per building. Only defects with recorded status periods are counted. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -316,33 +513,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "analytics"
                 ],
-                "summary": "Get building by id",
+                "summary": "SLA percentiles by building",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Building ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.BuildingResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.BuildingSLA"
+                            }
                         }
                     },
                     "400": {
-                        "description": "invalid id",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "building not found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -353,49 +612,113 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Delete building by id",
+                ]
+            }
+        },
+        "/api/analytics/sla/by-responsible": {
+            "get": {
+                "description": "p50/p90/p95 of time to first response, time to close and time spent in each status (hours), per responsible user. Unassigned defects and defects without recorded status periods are not counted. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "analytics"
                 ],
-                "summary": "Delete building",
+                "summary": "SLA percentiles by responsible",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Building ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully deleted building with id {id}",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ResponsibleSLA"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -406,15 +729,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "patch": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Partially update building (name/address/stage)",
+                ]
+            }
+        },
+        "/api/analytics/summary": {
+            "get": {
+                "description": "Total, open, overdue, closed and cancelled defect counts and average time to close (hours). Open and overdue exclude the workflow's final statuses; closed counts the workflow's closed status, cancelled the other final statuses. Accepts the same filters as the defect list.",
                 "consumes": [
                     "application/json"
                 ],
@@ -422,42 +747,92 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "buildings"
+                    "analytics"
                 ],
-                "summary": "Update building",
+                "summary": "Defect summary",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Building ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
                     {
-                        "description": "Update payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateBuildingRequest"
-                        }
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.BuildingResponse"
+                            "$ref": "#/definitions/handlers.AnalyticsSummaryResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -468,12 +843,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/comments": {
+        "/api/attachments/{id}": {
             "get": {
-                "description": "Get all comments for a specific defect",
+                "description": "Get defect attachment by attachment ID, with a short-lived signed download_url",
                 "consumes": [
                     "application/json"
                 ],
@@ -481,15 +861,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "comments"
+                    "defect-attachments"
                 ],
-                "summary": "List comments",
+                "summary": "Get defect attachment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Defect ID",
-                        "name": "defect_id",
-                        "in": "query",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -497,10 +877,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.CommentResponse"
-                            }
+                            "$ref": "#/definitions/handlers.DefectAttachmentResponse"
                         }
                     },
                     "400": {
@@ -509,6 +886,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -521,15 +904,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Create a new comment for a specific defect. Requires authentication.",
+                ]
+            },
+            "delete": {
+                "description": "Delete a defect attachment by attachment ID. Its file, thumbnail and preview are removed from storage once no other attachment references the same file.",
                 "consumes": [
                     "application/json"
                 ],
@@ -537,25 +920,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "comments"
+                    "defect-attachments"
                 ],
-                "summary": "Create a comment",
+                "summary": "Delete defect attachment",
                 "parameters": [
                     {
-                        "description": "Comment payload",
-                        "name": "comment",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CreateCommentRequest"
-                        }
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "attachment deleted successfully",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CommentResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -576,36 +960,50 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/comments/{id}": {
+        "/api/attachments/{id}/download": {
             "get": {
-                "description": "Get a comment by ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Stream the file of a defect attachment. Requires a Bearer token or a valid signed link (download_url from the attachment response: expires and signature query params). With EXIF_STRIP enabled, JPEG and HEIC photos are served without EXIF metadata (orientation is kept).",
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "comments"
+                    "defect-attachments"
                 ],
-                "summary": "Get a comment",
+                "summary": "Download defect attachment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Comment ID",
+                        "description": "Attachment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Signed link expiry (unix seconds)",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed link signature",
+                        "name": "signature",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CommentResponse"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -614,8 +1012,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "link has expired or signature is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "attachment or file not found",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -626,39 +1036,50 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Delete a comment by ID. Only users with role \"observer\" are allowed.",
-                "consumes": [
-                    "application/json"
-                ],
+                ]
+            }
+        },
+        "/api/attachments/{id}/preview": {
+            "get": {
+                "description": "Stream the medium preview (JPEG, at most 1280 px on the longer side) of an image attachment. Requires a Bearer token or a valid signed link (preview_url from the attachment response). Returns 404 while the preview is not generated yet (see preview_status).",
                 "produces": [
-                    "application/json"
+                    "image/jpeg"
                 ],
                 "tags": [
-                    "comments"
+                    "defect-attachments"
                 ],
-                "summary": "Delete a comment",
+                "summary": "Download defect attachment preview",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Comment ID",
+                        "description": "Attachment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Signed link expiry (unix seconds)",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed link signature",
+                        "name": "signature",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully deleted comment with id {id}",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -667,14 +1088,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "403": {
-                        "description": "Forbidden",
+                        "description": "link has expired or signature is invalid",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "attachment or preview not found",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -685,51 +1112,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/api/defects": {
+        "/api/attachments/{id}/thumbnail": {
             "get": {
-                "description": "Retrieve defects with optional filters and pagination",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Stream the thumbnail (JPEG, at most 320 px on the longer side) of an image attachment. Requires a Bearer token or a valid signed link (thumbnail_url from the attachment response). Returns 404 while the thumbnail is not generated yet (see preview_status).",
                 "produces": [
-                    "application/json"
+                    "image/jpeg"
                 ],
                 "tags": [
-                    "defects"
+                    "defect-attachments"
                 ],
-                "summary": "List defects",
+                "summary": "Download defect attachment thumbnail",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter by status",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Filter by building id",
-                        "name": "building_id",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "Filter by responsible user id",
-                        "name": "responsible_id",
-                        "in": "query"
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Limit number of results (default 100)",
-                        "name": "limit",
+                        "description": "Signed link expiry (unix seconds)",
+                        "name": "expires",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Offset for pagination (default 0)",
-                        "name": "offset",
+                        "type": "string",
+                        "description": "Signed link signature",
+                        "name": "signature",
                         "in": "query"
                     }
                 ],
@@ -737,14 +1155,29 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.DefectResponse"
-                            }
+                            "type": "file"
                         }
                     },
                     "400": {
-                        "description": "invalid query param",
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "link has expired or signature is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "attachment or thumbnail not found",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -755,15 +1188,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Create a defect. Requires authentication.",
+                ]
+            }
+        },
+        "/api/auth/login": {
+            "post": {
+                "description": "Validate credentials, open a new session and return a short-lived access token and a refresh token with expiry seconds",
                 "consumes": [
                     "application/json"
                 ],
@@ -771,35 +1206,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defects"
+                    "auth"
                 ],
-                "summary": "Create defect",
+                "summary": "Login and obtain JWT",
                 "parameters": [
                     {
-                        "description": "Defect payload",
+                        "description": "Login payload",
                         "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateDefectRequest"
+                            "$ref": "#/definitions/handlers.LoginRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.DefectResponse"
+                            "$ref": "#/definitions/handlers.TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "invalid request body or missing fields",
+                        "description": "invalid request body",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "unauthenticated",
+                        "description": "invalid credentials",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -813,9 +1248,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/defects/{id}": {
-            "get": {
-                "description": "Retrieve defect by numeric id",
+        "/api/auth/logout": {
+            "post": {
+                "description": "Revoke the session the refresh token belongs to. Access tokens of the session stop working immediately. Repeated logout is not an error.",
                 "consumes": [
                     "application/json"
                 ],
@@ -823,33 +1258,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defects"
+                    "auth"
                 ],
-                "summary": "Get defect by id",
+                "summary": "Logout",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Defect ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh token",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.DefectResponse"
-                        }
+                    "204": {
+                        "description": "session revoked"
                     },
                     "400": {
-                        "description": "invalid id",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "defect not found",
+                        "description": "invalid request body or malformed refresh token",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -861,48 +1289,47 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Delete defect by id",
+            }
+        },
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and a new refresh token. The presented refresh token becomes invalid; presenting it again revokes the whole session.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "defects"
+                    "auth"
                 ],
-                "summary": "Delete defect",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Defect ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh token",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully deleted defect with id {id}",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid request body",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "invalid, expired, reused or revoked refresh token",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -914,14 +1341,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Change status of a defect. Allowed status transitions depend on user role (engineer/manager). Allowed status values: new, in_progress, review, closed.",
+            }
+        },
+        "/api/auth/register": {
+            "post": {
+                "description": "Create a new user account (no JWT returned). Default role = \"engineer\".",
                 "consumes": [
                     "application/json"
                 ],
@@ -929,54 +1353,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defects"
+                    "auth"
                 ],
-                "summary": "Update defect status",
+                "summary": "Register a user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Defect ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "New status",
+                        "description": "Registration payload",
                         "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.UpdateStatusReq"
+                            "$ref": "#/definitions/handlers.RegisterRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.DefectResponse"
+                            "$ref": "#/definitions/handlers.UserResponse"
                         }
                     },
                     "400": {
-                        "description": "invalid id or request body or missing status",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "unauthenticated",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "insufficient permissions or role cannot set this status",
+                        "description": "invalid request body or missing fields",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "defect not found",
+                    "409": {
+                        "description": "user already exists",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -990,9 +1395,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/defects/{id}/attachments": {
+        "/api/buildings": {
             "get": {
-                "description": "Get all attachments for a specific defect",
+                "description": "Retrieve buildings page by page (offset or keyset cursor on created_at, id)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1000,36 +1405,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "defect-attachments"
+                    "buildings"
                 ],
-                "summary": "List defect attachments",
+                "summary": "List buildings",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Defect ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.DefectAttachmentResponse"
-                            }
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_BuildingResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                        "description": "invalid query param",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1043,53 +1450,37 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Upload a file for a specific defect. Requires authentication.",
+                "description": "Create a new building record",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "defect-attachments"
+                    "buildings"
                 ],
-                "summary": "Upload defect attachment",
+                "summary": "Create building",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Defect ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "File to upload",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Building payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateBuildingRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.DefectAttachmentResponse"
+                            "$ref": "#/definitions/handlers.BuildingResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/common.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                        "description": "invalid request body or missing fields",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1100,17 +1491,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            }
-        },
-        "/api/users": {
-            "get": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Retrieve list of all users",
+                ]
+            }
+        },
+        "/api/buildings/trash": {
+            "get": {
+                "description": "Retrieve soft-deleted buildings page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1118,17 +1509,52 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "buildings"
+                ],
+                "summary": "List deleted buildings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.UserResponse"
-                            }
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DeletedBuildingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
                     "500": {
@@ -1137,14 +1563,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/api/buildings/{id}": {
+            "get": {
+                "description": "Retrieve building by numeric id",
                 "consumes": [
                     "application/json"
                 ],
@@ -1152,25 +1581,71 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "buildings"
                 ],
-                "summary": "Create a new user",
+                "summary": "Get building by id",
                 "parameters": [
                     {
-                        "description": "New user data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "Building ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUserRequest"
+                            "$ref": "#/definitions/handlers.BuildingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "building not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft-delete building by id. The building can be restored or purged from trash. A building with defects outside the trash cannot be deleted: delete its defects first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "buildings"
+                ],
+                "summary": "Delete building",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Building ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Successfully deleted building with id {id}",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1179,8 +1654,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "409": {
-                        "description": "Conflict",
+                        "description": "building still has defects",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1191,17 +1678,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            }
-        },
-        "/api/users/{id}": {
-            "get": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Retrieve user by numeric id",
+                ]
+            },
+            "patch": {
+                "description": "Partially update building (name/address/stage/coordinates). Latitude and longitude are updated together.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1209,33 +1694,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "buildings"
                 ],
-                "summary": "Get user by id",
+                "summary": "Update building",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Building ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Update payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateBuildingRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
+                            "$ref": "#/definitions/handlers.BuildingResponse"
                         }
                     },
                     "400": {
-                        "description": "invalid id",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "user not found",
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1246,15 +1740,75 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
+                ]
+            }
+        },
+        "/api/buildings/{id}/attachments/export": {
+            "get": {
+                "description": "Stream a ZIP archive with the files of all defects of the building (defects in the trash are not included). Each defect gets its own folder \"\u003cdefect id\u003e_\u003ctitle\u003e/\" with comment attachments in its \"comments/\" subfolder; manifest.csv at the root lists every file. The archive is streamed as it is built, without Content-Length.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "attachment-export"
+                ],
+                "summary": "Export building attachments as ZIP",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Building ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "description": "Delete user by numeric id",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/buildings/{id}/purge": {
+            "delete": {
+                "description": "Permanently delete a soft-deleted building. Not allowed while any defect (including deleted ones) references it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1262,13 +1816,13 @@ const docTemplate = `{
                     "text/plain"
                 ],
                 "tags": [
-                    "users"
+                    "buildings"
                 ],
-                "summary": "Delete user",
+                "summary": "Purge building",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Building ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1276,7 +1830,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully deleted user with id {id}",
+                        "description": "Permanently deleted building with id {id}",
                         "schema": {
                             "type": "string"
                         }
@@ -1287,8 +1841,26 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "deleted building not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "building still has defects",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1299,15 +1871,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
-                }
-            },
-            "patch": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Update user's name and/or lastname",
+                ]
+            }
+        },
+        "/api/buildings/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted building",
                 "consumes": [
                     "application/json"
                 ],
@@ -1315,32 +1889,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "buildings"
                 ],
-                "summary": "Update user",
+                "summary": "Restore building",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Building ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Update payload",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateUserRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserResponse"
+                            "$ref": "#/definitions/handlers.BuildingResponse"
                         }
                     },
                     "400": {
@@ -1349,8 +1914,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "deleted building not found",
                         "schema": {
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
@@ -1361,126 +1938,3692 @@ const docTemplate = `{
                             "$ref": "#/definitions/common.ErrorResponse"
                         }
                     }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comment-attachments/{id}": {
+            "get": {
+                "description": "Get comment attachment by attachment ID, with a short-lived signed download_url",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comment-attachments"
+                ],
+                "summary": "Get comment attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentAttachmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "description": "Delete a comment attachment. Its file is removed from storage once no other attachment references it. Allowed for the comment author and for observer and manager roles.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comment-attachments"
+                ],
+                "summary": "Delete comment attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "attachment deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comment-attachments/{id}/download": {
+            "get": {
+                "description": "Download the file of a comment attachment. Requires a Bearer token or a valid signed link (download_url from the attachment response: expires and signature query params). With EXIF_STRIP enabled, JPEG and HEIC photos are served without EXIF metadata (orientation is kept).",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "comment-attachments"
+                ],
+                "summary": "Download comment attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Signed link expiry (unix seconds)",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed link signature",
+                        "name": "signature",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "link has expired or signature is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "attachment or file not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments": {
+            "get": {
+                "description": "Get comment threads for a specific defect. Top-level comments are paged (offset or keyset cursor on created_at, id), sorted by sort and filtered by author and creation date; total counts matching top-level comments. Each of them carries all its replies nested in replies, oldest first. Replies to a deleted comment are hidden together with it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "List comments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "defect_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by author user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at (oldest first) or -created_at (newest first, default)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset, keep the same sort)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found or in trash",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new comment for a specific defect. Requires authentication. Set parent_id to reply to another comment of the same defect. Every @login in the text must be an existing user; mentioned users are listed in the response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Create a comment",
+                "parameters": [
+                    {
+                        "description": "Comment payload",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/trash": {
+            "get": {
+                "description": "Get soft-deleted comments page by page, most recently deleted first (offset or keyset cursor on deleted_at, id). Optional filter by defect.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "List deleted comments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "defect_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DeletedCommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/{id}": {
+            "get": {
+                "description": "Get a comment by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Get a comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft-delete a comment by ID. Only users with role \"observer\" are allowed. The comment can be restored or purged from trash; attachment files are kept until the comment is purged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Delete a comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted comment with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "patch": {
+                "description": "Edit the text of a comment. Only the author can edit, and only within the configured edit window after creation (COMMENT_EDIT_WINDOW). The previous text is kept as a revision. Mentions are re-parsed from the new text.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Edit a comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New text",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "not the author or edit window has expired",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/{id}/attachments": {
+            "get": {
+                "description": "Get all attachments for a specific comment. Each attachment carries a short-lived signed download_url.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comment-attachments"
+                ],
+                "summary": "List comment attachments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CommentAttachmentResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Upload a file for a specific comment. Allowed for the comment author and for observer and manager roles. The file type is detected from its content and must be in the allow-list; images and other files have separate size limits. Files are stored once per content (SHA-256): instead of uploading a file again, pass the hash of a file already on the server.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comment-attachments"
+                ],
+                "summary": "Upload comment attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload (or hash)",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of a file already on the server, instead of file",
+                        "name": "hash",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name for an attachment created by hash",
+                        "name": "filename",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentAttachmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "comment or file with this hash not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "file is too large",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/{id}/purge": {
+            "delete": {
+                "description": "Permanently delete a soft-deleted comment with its attachments, revisions and mentions. Its replies move up to the purged comment's parent. Attachment files no longer referenced by other attachments are removed after the transaction commits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Purge a comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Permanently deleted comment with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted comment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Restore a comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/comments/{id}/revisions": {
+            "get": {
+                "description": "Get previous versions of a comment, oldest first. The current text is in the comment itself.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "List comment revisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Comment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CommentRevisionResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/defects": {
+            "get": {
+                "description": "Retrieve defects with optional filters, sorting and pagination (offset or keyset cursor on created_at, id). Unknown query params and sort fields are rejected with 400.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "List defects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort fields: deadline, priority, created_at, updated_at; prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset or sort)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a defect. Requires authentication. The defect starts in the initial status of the workflow (default \"new\").",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Create defect",
+                "parameters": [
+                    {
+                        "description": "Defect payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateDefectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request body or missing fields",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/trash": {
+            "get": {
+                "description": "Retrieve soft-deleted defects page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "List deleted defects",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DeletedDefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}": {
+            "get": {
+                "description": "Retrieve defect by numeric id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Get defect by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft-delete defect by id. The defect is hidden from all queries and can be restored or purged from trash. The deletion is recorded in the defect history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Delete defect",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted defect with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "patch": {
+                "description": "Partially update a defect. Any subset of the create fields may be sent. Referenced building and responsible user must exist, priority must be one of low/medium/high. Status changes follow the same workflow rules as the status endpoint.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Update defect",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateDefectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id, request body or field value",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "role cannot perform this transition",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "status transition not allowed from current status",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/attachments": {
+            "get": {
+                "description": "Get all attachments for a specific defect. Each attachment carries a short-lived signed download_url.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defect-attachments"
+                ],
+                "summary": "List defect attachments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.DefectAttachmentResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Upload a file for a specific defect. Requires authentication. The file type is detected from its content and must be in the allow-list (by default JPEG, PNG, WebP, HEIC and PDF); images and other files have separate size limits. Thumbnails and previews of images are generated in the background: the response has preview_status \"pending\" until they are ready. EXIF of JPEG and HEIC photos (capture time, device, orientation, GPS) is stored with the attachment; photos taken far from the building are flagged with far_from_building. Files are stored once per content (SHA-256): instead of uploading a file again, pass the hash of a file already on the server.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defect-attachments"
+                ],
+                "summary": "Upload defect attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload (or hash)",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of a file already on the server, instead of file",
+                        "name": "hash",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name for an attachment created by hash",
+                        "name": "filename",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectAttachmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect or file with this hash not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "file is too large",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/attachments/export": {
+            "get": {
+                "description": "Stream a ZIP archive with all files of the defect: defect attachments in the folder \"\u003cdefect id\u003e_\u003ctitle\u003e/\", comment attachments in its \"comments/\" subfolder, and manifest.csv at the root. The archive is streamed as it is built, without Content-Length. Files missing in storage are skipped and marked \"missing\" in the manifest. With EXIF_STRIP enabled, photos are exported without EXIF metadata.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "attachment-export"
+                ],
+                "summary": "Export defect attachments as ZIP",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/history": {
+            "get": {
+                "description": "Returns per-field changes of the defect (old value, new value, who and when) page by page in chronological order (offset or keyset cursor on changed_at, id).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Get defect history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DefectHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id or query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/purge": {
+            "delete": {
+                "description": "Permanently delete a soft-deleted defect together with its comments, comment revisions, attachments and history in one transaction. Attachment files are removed after the transaction commits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Purge defect",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Permanently deleted defect with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted defect. Both deletion and restore are recorded in the defect history as changes of the deleted_at field.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Restore defect",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/status": {
+            "patch": {
+                "description": "Change status of a defect. Only transitions described in the workflow (from current status, for the user's role) are allowed. Some transitions require a comment, which is saved as a defect comment; @mentions in it work as in POST /api/comments (every @login must be an existing user).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "Update defect status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateStatusReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DefectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id or request body, missing status or comment, unknown mentioned user",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "role cannot perform this transition",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "status transition not allowed from current status",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/defects/{id}/transitions": {
+            "get": {
+                "description": "Returns workflow transitions that the current user can apply to the defect from its current status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "defects"
+                ],
+                "summary": "List available status transitions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Defect ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.TransitionResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "defect not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/search": {
+            "get": {
+                "description": "Full-text search (Russian and English stemming) over defect title and description, comment text and building name and address. Results are defects ordered by relevance, with highlighted fragments. Accepts the same filters as the defect list. q supports web search syntax: \"phrase\", OR, -exclude.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Full-text search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated list allowed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority (low, medium, high), comma-separated list allowed",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by building id",
+                        "name": "building_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by responsible user id",
+                        "name": "responsible_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by creator user id",
+                        "name": "created_by_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (2006-01-02 includes the whole day)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)",
+                        "name": "deadline_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deadline at or before (2006-01-02 includes the whole day)",
+                        "name": "deadline_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only open defects with a past deadline",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only defects without a responsible user",
+                        "name": "unassigned",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_SearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "missing q or invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users": {
+            "get": {
+                "description": "Retrieve users page by page (offset or keyset cursor on created_at, id)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create a new user",
+                "parameters": [
+                    {
+                        "description": "New user data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users/trash": {
+            "get": {
+                "description": "Retrieve soft-deleted users page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List deleted users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_DeletedUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query param",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users/{id}": {
+            "get": {
+                "description": "Retrieve user by numeric id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "description": "Soft-delete user by numeric id. A deleted user cannot log in and all the user's sessions are revoked; the user can be restored or purged from trash.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully deleted user with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "patch": {
+                "description": "Update user's name, lastname and/or role. Changing the role revokes all user's sessions, so the user has to log in again; the new role applies to the user's requests immediately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update payload",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users/{id}/mentions": {
+            "get": {
+                "description": "Get comments that mention the user via @login, newest first, page by page (offset or keyset cursor on created_at, id). Users read their own mentions (id may be \"me\"); observer and manager roles may read anyone's.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "List user mentions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID or me",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit number of results (default 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page (not combinable with offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse-handlers_CommentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "mentions of another user",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users/{id}/purge": {
+            "delete": {
+                "description": "Permanently delete a soft-deleted user. Not allowed while defects, comments or history entries (including deleted ones) reference the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Purge user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Permanently deleted user with id {id}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted user not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "user is still referenced",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/api/users/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Restore user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted user not found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/common.ErrorResponse"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        }
+    },
+    "definitions": {
+        "auth.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "Ed25519",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JWK"
+                    }
+                }
+            }
+        },
+        "common.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "example: user not found",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AnalyticsSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "avg_close_hours": {
+                    "description": "среднее время от создания до закрытия в часах, null если закрытых нет\nexample: 73.5",
+                    "type": "number"
+                },
+                "cancelled": {
+                    "description": "в остальных завершённых статусах схемы (final), например cancelled\nexample: 15",
+                    "type": "integer"
+                },
+                "closed": {
+                    "description": "в статусе closed схемы workflow (работа выполнена)\nexample: 130",
+                    "type": "integer"
+                },
+                "open": {
+                    "description": "example: 95",
+                    "type": "integer"
+                },
+                "overdue": {
+                    "description": "example: 12",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "example: 240",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.BuildingResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.BuildingSLA": {
+            "type": "object",
+            "properties": {
+                "building_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "close_hours": {
+                    "description": "от создания до закрытия (только закрытые дефекты)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.Percentiles"
+                        }
+                    ]
+                },
+                "first_response_hours": {
+                    "description": "от создания до первого перехода из начального статуса схемы",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.Percentiles"
+                        }
+                    ]
+                },
+                "name": {
+                    "description": "example: ЖК Солнечный",
+                    "type": "string"
+                },
+                "time_in_status_hours": {
+                    "description": "суммарное время в каждом статусе",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/handlers.Percentiles"
+                    }
+                }
+            }
+        },
+        "handlers.BuildingStat": {
+            "type": "object",
+            "properties": {
+                "building_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "example: ЖК Солнечный",
+                    "type": "string"
+                },
+                "open": {
+                    "description": "example: 12",
+                    "type": "integer"
+                },
+                "overdue": {
+                    "description": "example: 3",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "example: 40",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CommentAttachmentResponse": {
+            "type": "object",
+            "properties": {
+                "comment_id": {
+                    "description": "example: 3",
+                    "type": "integer"
+                },
+                "content_type": {
+                    "description": "MIME-тип, определённый по содержимому файла\nexample: image/jpeg",
+                    "type": "string"
+                },
+                "download_url": {
+                    "description": "короткоживущая подписанная ссылка на файл (только в ответах эндпоинтов вложений)\nexample: /api/comment-attachments/1/download?expires=1760456330\u0026signature=Zk9v...",
+                    "type": "string"
+                },
+                "filename": {
+                    "description": "исходное имя файла (очищенное от пути и служебных символов)\nexample: repaired_wall.jpg",
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "SHA-256 содержимого; по нему файл можно прикрепить повторно без загрузки (поле hash формы).\nПусто у вложений, загруженных до дедупликации\nexample: 5e884898da28047151d0e56f8dc62927e7c8e9f1b2a3d4c5b6a7980f1e2d3c4b",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "размер файла, байт\nexample: 1048576",
+                    "type": "integer"
+                },
+                "url": {
+                    "description": "ключ файла в хранилище\nexample: files/5e884898da28047151d0e56f8dc62927e7c8e9f1b2a3d4c5b6a7980f1e2d3c4b.jpg",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CommentResponse": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CommentAttachmentResponse"
+                    }
+                },
+                "created_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "created_by": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "created_by_person_id": {
+                    "description": "example: 2",
+                    "type": "integer"
+                },
+                "defect_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "edited_at": {
+                    "description": "время последней правки, null если комментарий не редактировался\nexample: 2025-10-11T14:05:00Z",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "mentions": {
+                    "description": "пользователи, упомянутые в тексте через @login",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SimpleUser"
+                    }
+                },
+                "parent_id": {
+                    "description": "null для комментария верхнего уровня\nexample: 5",
+                    "type": "integer"
+                },
+                "replies": {
+                    "description": "ответы, старые сначала; заполняется только в списке GET /api/comments",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CommentResponse"
+                    }
+                },
+                "text": {
+                    "description": "example: Broken glass needs replacement",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CommentRevisionResponse": {
+            "type": "object",
+            "properties": {
+                "comment_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "edited_by": {
+                    "description": "example: 2",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "replaced_at": {
+                    "description": "когда её заменили новой\nexample: 2025-10-11T14:05:00Z",
+                    "type": "string"
+                },
+                "text": {
+                    "description": "example: Broken glass needs replacement",
+                    "type": "string"
+                },
+                "written_at": {
+                    "description": "когда эта версия была написана\nexample: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CountItem": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "example: 17",
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "example: in_progress",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateBuildingRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "example: Невский пр., 1",
+                    "type": "string"
+                },
+                "latitude": {
+                    "description": "широта и долгота объекта, задаются вместе\nexample: 59.9358",
+                    "type": "number"
+                },
+                "longitude": {
+                    "description": "example: 30.3259",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "example: Дом на Невском",
+                    "type": "string"
+                },
+                "stage": {
+                    "description": "example: построено",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateCommentRequest": {
+            "type": "object",
+            "properties": {
+                "defect_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "parent_id": {
+                    "description": "ID комментария того же дефекта, на который это ответ\nexample: 5",
+                    "type": "integer"
+                },
+                "text": {
+                    "description": "example: Found a broken window on 3rd floor",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateDefectRequest": {
+            "type": "object",
+            "properties": {
+                "building_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "deadline": {
+                    "description": "example: 2025-12-31 23:59:59\ndeadline in layout \"2006-01-02 15:04:05\"",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "example: Описание дефекта...",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "example: high",
+                    "type": "string"
+                },
+                "responsible_person_id": {
+                    "description": "example: 2",
+                    "type": "integer"
+                },
+                "title": {
+                    "description": "example: Трещина в стене",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateUserRequest": {
+            "type": "object",
+            "properties": {
+                "lastname": {
+                    "description": "example: Ivanov",
+                    "type": "string"
+                },
+                "login": {
+                    "description": "example: ivan123",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "example: Ivan",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "example: passw0rd",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "example: observer",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DefectAttachmentResponse": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "MIME-тип, определённый по содержимому файла\nexample: image/png",
+                    "type": "string"
+                },
+                "defect_id": {
+                    "description": "example: 2",
+                    "type": "integer"
+                },
+                "device": {
+                    "description": "производитель и модель камеры\nexample: Apple iPhone 14 Pro",
+                    "type": "string"
+                },
+                "distance_from_building": {
+                    "description": "расстояние от места съёмки до здания дефекта, м; null, если у фото или здания нет координат\nexample: 42",
+                    "type": "number"
+                },
+                "download_url": {
+                    "description": "короткоживущая подписанная ссылка на файл, работает без Authorization (например, в \u003cimg src\u003e)\nexample: /api/attachments/1/download?expires=1760456330\u0026signature=Zk9v...",
+                    "type": "string"
+                },
+                "far_from_building": {
+                    "description": "фото снято дальше допустимого расстояния от здания (PHOTO_MAX_DISTANCE)\nexample: false",
+                    "type": "boolean"
+                },
+                "filename": {
+                    "description": "исходное имя файла (очищенное от пути и служебных символов)\nexample: broken_wall.png",
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "SHA-256 содержимого; по нему файл можно прикрепить повторно без загрузки (поле hash формы).\nПусто у вложений, загруженных до дедупликации\nexample: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "latitude": {
+                    "description": "example: 59.93581",
+                    "type": "number"
+                },
+                "longitude": {
+                    "description": "example: 30.32612",
+                    "type": "number"
+                },
+                "orientation": {
+                    "description": "ориентация по EXIF (1..8), 0 — не указана\nexample: 6",
+                    "type": "integer"
+                },
+                "preview_status": {
+                    "description": "состояние уменьшенных копий: pending, ready, failed или none (не изображение)\nexample: ready",
+                    "type": "string"
+                },
+                "preview_url": {
+                    "description": "подписанная ссылка на превью (до 1280 px, JPEG), только когда preview_status = ready\nexample: /api/attachments/1/preview?expires=1760456330\u0026signature=Zk9v...",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "размер файла, байт\nexample: 482113",
+                    "type": "integer"
+                },
+                "taken_at": {
+                    "description": "время съёмки\nexample: 2025-10-07T14:03:12+03:00",
+                    "type": "string"
+                },
+                "thumbnail_url": {
+                    "description": "подписанная ссылка на миниатюру (до 320 px, JPEG), только когда preview_status = ready\nexample: /api/attachments/1/thumbnail?expires=1760456330\u0026signature=Zk9v...",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "ключ файла в хранилище\nexample: files/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DefectHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "changed_by": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "defect_id": {
+                    "description": "example: 3",
+                    "type": "integer"
+                },
+                "field": {
+                    "description": "example: status",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "example: 15",
+                    "type": "integer"
+                },
+                "new_value": {
+                    "description": "example: review",
+                    "type": "string"
+                },
+                "old_value": {
+                    "description": "example: in_progress",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DefectMetrics": {
+            "type": "object",
+            "properties": {
+                "close_hours": {
+                    "description": "часы от создания до (последнего) перехода в статус closed схемы, null если дефект не закрыт\nexample: 96",
+                    "type": "number"
+                },
+                "first_response_hours": {
+                    "description": "часы от создания до первого перехода из начального статуса схемы, null пока дефект не взят в работу\nexample: 5.25",
+                    "type": "number"
+                },
+                "time_in_status_hours": {
+                    "description": "часы в каждом статусе (для текущего статуса — до текущего момента)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                }
+            }
+        },
+        "handlers.DefectResponse": {
+            "type": "object",
+            "properties": {
+                "building": {
+                    "$ref": "#/definitions/handlers.SimpleBuilding"
+                },
+                "building_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "created_by_person_id": {
+                    "type": "integer"
+                },
+                "deadline": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/handlers.DefectMetrics"
+                },
+                "priority": {
+                    "type": "string"
+                },
+                "responsible": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "responsible_person_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by_person_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeletedBuildingResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "deleted_by_person_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeletedCommentResponse": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CommentAttachmentResponse"
+                    }
+                },
+                "created_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "created_by": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "created_by_person_id": {
+                    "description": "example: 2",
+                    "type": "integer"
+                },
+                "defect_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "deleted_by_person_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "edited_at": {
+                    "description": "время последней правки, null если комментарий не редактировался\nexample: 2025-10-11T14:05:00Z",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "mentions": {
+                    "description": "пользователи, упомянутые в тексте через @login",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SimpleUser"
+                    }
+                },
+                "parent_id": {
+                    "description": "null для комментария верхнего уровня\nexample: 5",
+                    "type": "integer"
+                },
+                "replies": {
+                    "description": "ответы, старые сначала; заполняется только в списке GET /api/comments",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CommentResponse"
+                    }
+                },
+                "text": {
+                    "description": "example: Broken glass needs replacement",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeletedDefectResponse": {
+            "type": "object",
+            "properties": {
+                "building": {
+                    "$ref": "#/definitions/handlers.SimpleBuilding"
+                },
+                "building_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "created_by_person_id": {
+                    "type": "integer"
+                },
+                "deadline": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "deleted_by_person_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/handlers.DefectMetrics"
+                },
+                "priority": {
+                    "type": "string"
+                },
+                "responsible": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "responsible_person_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by_person_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeletedUserResponse": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "description": "example: 2025-10-11T14:00:00Z",
+                    "type": "string"
+                },
+                "deleted_by_person_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastname": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ListResponse-handlers_BuildingResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BuildingResponse"
+                    }
+                },
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
-        }
-    },
-    "definitions": {
-        "common.ErrorResponse": {
+        },
+        "handlers.ListResponse-handlers_CommentResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "description": "example: user not found",
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CommentResponse"
+                    }
+                },
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
+                },
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.BuildingResponse": {
+        "handlers.ListResponse-handlers_DefectHistoryResponse": {
             "type": "object",
             "properties": {
-                "address": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DefectHistoryResponse"
+                    }
+                },
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
                 },
-                "id": {
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
                     "type": "integer"
+                }
+            }
+        },
+        "handlers.ListResponse-handlers_DefectResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DefectResponse"
+                    }
                 },
-                "name": {
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
                 },
-                "stage": {
-                    "type": "string"
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.CommentResponse": {
+        "handlers.ListResponse-handlers_DeletedBuildingResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "description": "example: 2025-10-11T14:00:00Z",
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeletedBuildingResponse"
+                    }
+                },
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
                 },
-                "created_by": {
-                    "description": "example: 2",
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
                     "type": "integer"
+                }
+            }
+        },
+        "handlers.ListResponse-handlers_DeletedCommentResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeletedCommentResponse"
+                    }
                 },
-                "defect_id": {
-                    "description": "example: 1",
-                    "type": "integer"
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
+                    "type": "string"
                 },
-                "id": {
-                    "description": "example: 1",
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
                     "type": "integer"
+                }
+            }
+        },
+        "handlers.ListResponse-handlers_DeletedDefectResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeletedDefectResponse"
+                    }
                 },
-                "text": {
-                    "description": "example: Broken glass needs replacement",
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
+                },
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.CreateBuildingRequest": {
+        "handlers.ListResponse-handlers_DeletedUserResponse": {
             "type": "object",
             "properties": {
-                "address": {
-                    "description": "example: Невский пр., 1",
-                    "type": "string"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeletedUserResponse"
+                    }
                 },
-                "name": {
-                    "description": "example: Дом на Невском",
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
                 },
-                "stage": {
-                    "description": "example: построено",
-                    "type": "string"
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.CreateCommentRequest": {
+        "handlers.ListResponse-handlers_SearchResult": {
             "type": "object",
             "properties": {
-                "defect_id": {
-                    "description": "example: 1",
-                    "type": "integer"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SearchResult"
+                    }
                 },
-                "text": {
-                    "description": "example: Found a broken window on 3rd floor",
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
+                },
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.CreateDefectRequest": {
+        "handlers.ListResponse-handlers_UserResponse": {
             "type": "object",
             "properties": {
-                "building_id": {
-                    "description": "example: 1",
-                    "type": "integer"
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.UserResponse"
+                    }
                 },
-                "deadline": {
-                    "description": "example: 2025-12-31 23:59:59\ndeadline in layout \"2006-01-02 15:04:05\"",
+                "next_cursor": {
+                    "description": "example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9",
                     "type": "string"
                 },
-                "description": {
-                    "description": "example: Описание дефекта...",
+                "total": {
+                    "description": "всего записей с учётом фильтров\nexample: 240",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "description": "example: ivan123",
                     "type": "string"
                 },
-                "priority": {
-                    "description": "example: high",
+                "password": {
+                    "description": "example: passw0rd",
                     "type": "string"
-                },
-                "responsible_person_id": {
-                    "description": "example: 2",
+                }
+            }
+        },
+        "handlers.Percentiles": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "размер выборки\nexample: 42",
                     "type": "integer"
                 },
-                "status": {
-                    "description": "example: new",
-                    "type": "string"
+                "p50": {
+                    "description": "example: 4.5",
+                    "type": "number"
                 },
-                "title": {
-                    "description": "example: Трещина в стене",
+                "p90": {
+                    "description": "example: 26",
+                    "type": "number"
+                },
+                "p95": {
+                    "description": "example: 48.2",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "description": "example: 3f9c0a7d1e2b4c5d6e7f8091a2b3c4d5.q1w2e3r4t5y6u7i8o9p0",
                     "type": "string"
                 }
             }
         },
-        "handlers.CreateUserRequest": {
+        "handlers.RegisterRequest": {
             "type": "object",
             "properties": {
                 "lastname": {
@@ -1498,111 +5641,88 @@ const docTemplate = `{
                 "password": {
                     "description": "example: passw0rd",
                     "type": "string"
-                },
-                "role": {
-                    "description": "example: observer",
-                    "type": "string"
                 }
             }
         },
-        "handlers.DefectAttachmentResponse": {
+        "handlers.ResponsibleSLA": {
             "type": "object",
             "properties": {
-                "defect_id": {
-                    "description": "example: 2",
-                    "type": "integer"
+                "close_hours": {
+                    "description": "от создания до закрытия (только закрытые дефекты)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.Percentiles"
+                        }
+                    ]
                 },
-                "id": {
-                    "description": "example: 1",
-                    "type": "integer"
+                "first_response_hours": {
+                    "description": "от создания до первого перехода из начального статуса схемы",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.Percentiles"
+                        }
+                    ]
                 },
-                "url": {
-                    "description": "example: internal/uploads/defect_attachments/1759835216551583000_broken_wall.png",
-                    "type": "string"
+                "responsible": {
+                    "$ref": "#/definitions/handlers.SimpleUser"
+                },
+                "time_in_status_hours": {
+                    "description": "суммарное время в каждом статусе",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/handlers.Percentiles"
+                    }
                 }
             }
         },
-        "handlers.DefectResponse": {
+        "handlers.ResponsibleStat": {
             "type": "object",
             "properties": {
-                "building": {
-                    "$ref": "#/definitions/handlers.SimpleBuilding"
-                },
-                "building_id": {
-                    "type": "integer"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "created_by": {
-                    "$ref": "#/definitions/handlers.SimpleUser"
-                },
-                "created_by_person_id": {
+                "open": {
+                    "description": "example: 8",
                     "type": "integer"
                 },
-                "deadline": {
-                    "type": "string"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "id": {
+                "overdue": {
+                    "description": "example: 2",
                     "type": "integer"
                 },
-                "priority": {
-                    "type": "string"
-                },
                 "responsible": {
                     "$ref": "#/definitions/handlers.SimpleUser"
-                },
-                "responsible_person_id": {
-                    "type": "integer"
-                },
-                "status": {
-                    "type": "string"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "updated_at": {
-                    "type": "string"
-                },
-                "updated_by_person_id": {
-                    "type": "integer"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "handlers.SearchHighlight": {
             "type": "object",
             "properties": {
-                "login": {
-                    "description": "example: ivan123",
+                "comment_id": {
+                    "description": "только для field = comment\nexample: 12",
+                    "type": "integer"
+                },
+                "field": {
+                    "description": "title, description, comment, building_name или building_address\nexample: description",
                     "type": "string"
                 },
-                "password": {
-                    "description": "example: passw0rd",
+                "fragment": {
+                    "description": "example: Протечка на \u003cmark\u003e5 этаже\u003c/mark\u003e у лифта",
                     "type": "string"
                 }
             }
         },
-        "handlers.RegisterRequest": {
+        "handlers.SearchResult": {
             "type": "object",
             "properties": {
-                "lastname": {
-                    "description": "example: Ivanov",
-                    "type": "string"
-                },
-                "login": {
-                    "description": "example: ivan123",
-                    "type": "string"
+                "defect": {
+                    "$ref": "#/definitions/handlers.DefectResponse"
                 },
-                "name": {
-                    "description": "example: Ivan",
-                    "type": "string"
+                "highlights": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SearchHighlight"
+                    }
                 },
-                "password": {
-                    "description": "example: passw0rd",
-                    "type": "string"
+                "rank": {
+                    "description": "example: 0.75",
+                    "type": "number"
                 }
             }
         },
@@ -1654,12 +5774,41 @@ const docTemplate = `{
                     "description": "seconds until token expiration",
                     "type": "integer"
                 },
+                "refresh_expires_in": {
+                    "description": "seconds until refresh token expiration",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "description": "one-time token to obtain a new pair via /api/auth/refresh",
+                    "type": "string"
+                },
                 "token_type": {
                     "description": "token type, usually \"Bearer\"",
                     "type": "string"
                 }
             }
         },
+        "handlers.TransitionResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "example: in_progress",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "example: send_to_review",
+                    "type": "string"
+                },
+                "require_comment": {
+                    "description": "example: false",
+                    "type": "boolean"
+                },
+                "to": {
+                    "description": "example: review",
+                    "type": "string"
+                }
+            }
+        },
         "handlers.UpdateBuildingRequest": {
             "type": "object",
             "properties": {
@@ -1667,6 +5816,14 @@ const docTemplate = `{
                     "description": "example: Новый адрес",
                     "type": "string"
                 },
+                "latitude": {
+                    "description": "example: 59.9358",
+                    "type": "number"
+                },
+                "longitude": {
+                    "description": "example: 30.3259",
+                    "type": "number"
+                },
                 "name": {
                     "description": "example: Новый дом",
                     "type": "string"
@@ -1677,9 +5834,59 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.UpdateCommentRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "description": "example: Broken glass on the 3rd floor needs replacement",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateDefectRequest": {
+            "type": "object",
+            "properties": {
+                "building_id": {
+                    "description": "example: 1",
+                    "type": "integer"
+                },
+                "comment": {
+                    "description": "example: Переназначено на другую бригаду",
+                    "type": "string"
+                },
+                "deadline": {
+                    "description": "example: 2025-12-31 23:59:59",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "example: Уточнённое описание дефекта...",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "example: medium",
+                    "type": "string"
+                },
+                "responsible_person_id": {
+                    "description": "example: 3",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "example: in_progress",
+                    "type": "string"
+                },
+                "title": {
+                    "description": "example: Трещина в несущей стене",
+                    "type": "string"
+                }
+            }
+        },
         "handlers.UpdateStatusReq": {
             "type": "object",
             "properties": {
+                "comment": {
+                    "description": "example: Работы завершены, прошу принять\nКомментарий к смене статуса; обязателен для переходов с require_comment",
+                    "type": "string"
+                },
                 "status": {
                     "description": "example: in_progress\nВозможные значения: \"new\", \"in_progress\", \"review\", \"closed\" (зависит от вашей логики)",
                     "type": "string"
@@ -1696,6 +5903,10 @@ const docTemplate = `{
                 "name": {
                     "description": "example: Ivan",
                     "type": "string"
+                },
+                "role": {
+                    "description": "new role; changing it revokes all user's sessions\nexample: manager",
+                    "type": "string"
                 }
             }
         },
@@ -1,9 +1,20 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/cleanup"
 	"github.com/Quasar777/buildefect/app/backend/internal/config"
 	"github.com/Quasar777/buildefect/app/backend/internal/database/postgresql"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
 	"github.com/Quasar777/buildefect/app/backend/internal/routes"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/rs/zerolog"
@@ -26,6 +37,15 @@ func main() {
 
 	// Инициализация конфига
 	cfg := config.LoadConfig(logger)
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("insecure configuration")
+	}
+
+	// Ключи подписи access-токенов
+	keys, err := auth.LoadKeys(cfg.JWT)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to load jwt keys")
+	}
 
 	// Подключаемся к Postgres
 	pg, err := postgresql.Connect(cfg, logger)
@@ -38,7 +58,52 @@ func main() {
         }
     }()
 	
-	app := fiber.New()
+	// Загружаем схему переходов статусов дефекта
+	wf, err := workflow.Load(cfg.WorkflowFile)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to load defect workflow")
+	}
+	
+	// Хранилище файлов вложений (STORAGE_DRIVER: local или s3)
+	store, err := storage.New(context.Background(), cfg.Storage)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to init attachment storage")
+	}
+
+	// Токены и сеансы пользователей: короткий access-токен и одноразовый refresh-токен
+	tokens := auth.NewManager(pg.GormDB, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cfg.AuthCacheTTL)
+
+	// Ротация ключей без перезапуска: SIGHUP перечитывает JWT_KEYS_DIR
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			keys, err := auth.LoadKeys(cfg.JWT)
+			if err != nil {
+				logger.Error().Err(err).Msg("jwt keys reload failed, keeping current keys")
+				continue
+			}
+			tokens.SetKeys(keys)
+			logger.Info().Str("signing_kid", keys.SigningKeyID()).Msg("jwt keys reloaded")
+		}
+	}()
+
+	// Подписанные ссылки на скачивание вложений
+	signer := signedurl.New(cfg.FileURLSecret, cfg.FileURLTTL)
+
+	// Фоновая генерация миниатюр и превью фото вложений
+	previews := preview.NewWorker(pg.GormDB, store)
+	previews.Start(context.Background(), cfg.PreviewWorkers)
+
+	// Фоновая сверка хранилища с БД (по умолчанию выключена, CLEANUP_INTERVAL);
+	// на Postgres одновременно её выполняет только одна реплика
+	cleanup.NewCollector(pg.GormDB, store).Start(context.Background(), cfg.CleanupInterval, cfg.Cleanup)
+
+	// лимит тела запроса — самый большой разрешённый файл плюс запас на multipart-обвязку;
+	// точные лимиты по типу файла проверяются при загрузке
+	app := fiber.New(fiber.Config{
+		BodyLimit: int(cfg.Upload.MaxSize()) + 1<<20,
+	})
 
 	// cors
 	app.Use(cors.New(cors.Config{
@@ -48,15 +113,15 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	routes.RegisterUserRoutes(app, pg.GormDB, cfg.JWTSecret)
-	routes.RegisterBuildingRoutes(app, pg.GormDB, cfg.JWTSecret)
-	routes.RegisterDefectRoutes(app, pg.GormDB, cfg.JWTSecret)
-	routes.RegisterCommentsRoutes(app, pg.GormDB, cfg.JWTSecret)
-	routes.RegisterDefectAttachmentsRoutes(app, pg.GormDB, cfg.JWTSecret)
-	// TODO: create api for comment attachments
-
-	// статическая отдача файлов
-	app.Static("/uploads", "internal/uploads")
+	routes.RegisterUserRoutes(app, pg.GormDB, tokens)
+	routes.RegisterBuildingRoutes(app, pg.GormDB, tokens)
+	routes.RegisterDefectRoutes(app, pg.GormDB, tokens, wf, store)
+	routes.RegisterCommentsRoutes(app, pg.GormDB, tokens, cfg.CommentEditWindow, store)
+	routes.RegisterDefectAttachmentsRoutes(app, pg.GormDB, tokens, store, cfg.Upload, signer, previews, cfg.Photos)
+	routes.RegisterAnalyticsRoutes(app, pg.GormDB, tokens, wf)
+	routes.RegisterCommentAttachmentsRoutes(app, pg.GormDB, tokens, store, cfg.Upload, signer, cfg.Photos)
+	routes.RegisterSearchRoutes(app, pg.GormDB, tokens, wf)
+	routes.RegisterAttachmentExportRoutes(app, pg.GormDB, tokens, store, cfg.Photos)
 	
 	// swagger
     app.Get("/swagger/*", swagger.HandlerDefault)
@@ -0,0 +1,57 @@
+// Команда cleanup сверяет хранилище вложений с БД и выводит отчёт: файлы, на которые
+// не ссылается ни одна запись, записи вложений без дефекта или комментария и записи,
+// файла которых нет. Та же сверка может работать в фоне на сервере (CLEANUP_INTERVAL).
+// По умолчанию (CLEANUP_DRY_RUN=true) команда только выводит отчёт.
+//
+//	go run ./cmd/cleanup                               # только отчёт
+//	go run ./cmd/cleanup -dry-run=false                # удалить файлы без записей старше CLEANUP_MIN_AGE
+//	go run ./cmd/cleanup -dry-run=false -min-age 1h    # свой порог возраста файлов
+//	go run ./cmd/cleanup -dry-run=false -remove-missing # удалить и записи, файла которых нет
+//	go run ./cmd/cleanup -dry-run=false -max-orphan-share 1 # удалить, даже если без записей больше 20% файлов
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/cleanup"
+	"github.com/Quasar777/buildefect/app/backend/internal/config"
+	"github.com/Quasar777/buildefect/app/backend/internal/database/postgresql"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	zerolog.TimeFieldFormat = "02.01.2006 15:04:05.000"
+	logger := log.With().Logger()
+
+	cfg := config.LoadConfig(logger)
+
+	dryRun := flag.Bool("dry-run", cfg.Cleanup.DryRun, "only report, do not delete anything")
+	minAge := flag.Duration("min-age", cfg.Cleanup.MinAge, "do not delete files younger than this")
+	removeMissing := flag.Bool("remove-missing", cfg.Cleanup.RemoveMissing, "also delete attachment rows whose file is missing in storage")
+	maxOrphanShare := flag.Float64("max-orphan-share", cfg.Cleanup.MaxOrphanShare, "refuse to delete when more than this share of stored files is orphaned (0 disables the check)")
+	flag.Parse()
+
+	pg, err := postgresql.Connect(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to connect to postgres")
+	}
+	defer pg.Close()
+
+	ctx := context.Background()
+	store, err := storage.New(ctx, cfg.Storage)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to init attachment storage")
+	}
+
+	opts := cleanup.Options{DryRun: *dryRun, MinAge: *minAge, RemoveMissing: *removeMissing, MaxOrphanShare: *maxOrphanShare}
+	report, err := cleanup.NewCollector(pg.GormDB, store).Run(ctx, opts)
+	if err != nil {
+		// отчёт пишем и при отказе от удаления: по нему видно, что пошло не так
+		report.Log(logger)
+		logger.Fatal().Err(err).Msg("storage cleanup failed")
+	}
+	report.Log(logger)
+}
@@ -0,0 +1,68 @@
+// Команда previews строит миниатюры и превью для уже загруженных фото вложений дефектов:
+// для записей, созданных до появления превью, и для тех, что не успели обработаться
+// (очередь фоновой генерации живёт в памяти и пропадает при перезапуске).
+//
+//	go run ./cmd/previews            # pending
+//	go run ./cmd/previews -failed    # pending и failed
+//	go run ./cmd/previews -all       # пересобрать все изображения
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/config"
+	"github.com/Quasar777/buildefect/app/backend/internal/database/postgresql"
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	retryFailed := flag.Bool("failed", false, "also retry attachments whose previews failed")
+	all := flag.Bool("all", false, "regenerate previews for every attachment")
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = "02.01.2006 15:04:05.000"
+	logger := log.With().Logger()
+
+	cfg := config.LoadConfig(logger)
+
+	pg, err := postgresql.Connect(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to connect to postgres")
+	}
+	defer pg.Close()
+
+	ctx := context.Background()
+	store, err := storage.New(ctx, cfg.Storage)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("unable to init attachment storage")
+	}
+
+	statuses := []string{preview.StatusPending}
+	if *retryFailed {
+		statuses = append(statuses, preview.StatusFailed)
+	}
+	q := pg.GormDB.Model(&models.DefectAttachment{})
+	if !*all {
+		q = q.Where("preview_status IN ?", statuses)
+	}
+	var ids []uint
+	if err := q.Order("id").Pluck("id", &ids).Error; err != nil {
+		logger.Fatal().Err(err).Msg("failed to list attachments")
+	}
+
+	logger.Info().Int("count", len(ids)).Msg("generating attachment previews")
+	worker := preview.NewWorker(pg.GormDB, store)
+	failed := 0
+	for _, id := range ids {
+		if err := worker.Process(ctx, id); err != nil {
+			failed++
+			logger.Warn().Err(err).Uint("attachment_id", id).Msg("failed to generate previews")
+		}
+	}
+	logger.Info().Int("processed", len(ids)).Int("failed", failed).Msg("done")
+}
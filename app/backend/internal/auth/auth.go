@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Выдача и проверка токенов. Access-токен — короткоживущий JWT с идентификатором
+// сеанса (sid), refresh-токен — строка "<sid>.<секрет>"; в БД хранится только хэш секрета.
+// Refresh-токен одноразовый: при обновлении выдаётся новый, а повторное предъявление
+// старого считается кражей и отзывает сеанс.
+// Пользователь и роль каждого запроса берутся из БД, а не из claims токена; чтобы не ходить
+// в БД на каждый запрос, результат кэшируется по sid на короткое время (cacheTTL).
+
+var (
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrUserNotFound        = errors.New("user not found")
+)
+
+type Manager struct {
+	db         *gorm.DB
+	keys       atomic.Pointer[KeySet]
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+
+	// кэш сеансов по sid; cacheTTL = 0 — без кэша
+	cacheTTL  time.Duration
+	mu        sync.Mutex
+	cache     map[string]cachedIdentity
+	nextSweep time.Time
+	// растёт при каждом сбросе: ответ БД, прочитанный до сброса, в кэш не кладётся
+	generation uint64
+}
+
+type cachedIdentity struct {
+	identity Identity
+	expires  time.Time
+}
+
+func NewManager(db *gorm.DB, keys *KeySet, accessTTL, refreshTTL, cacheTTL time.Duration) *Manager {
+	m := &Manager{
+		db:         db,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		cacheTTL:   cacheTTL,
+		cache:      make(map[string]cachedIdentity),
+	}
+	m.keys.Store(keys)
+	return m
+}
+
+// SetKeys подменяет ключи на лету (ротация без перезапуска).
+func (m *Manager) SetKeys(keys *KeySet) {
+	m.keys.Store(keys)
+}
+
+// JWKS открытые ключи для проверки access-токенов другими сервисами.
+func (m *Manager) JWKS() JWKS {
+	return m.keys.Load().JWKS()
+}
+
+// Tokens пара токенов, выдаваемая при входе и при обновлении.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+}
+
+// Identity пользователь и сеанс из действующего access-токена.
+type Identity struct {
+	UserID    uint
+	Role      string
+	SessionID string
+}
+
+// StartSession открывает новый сеанс для пользователя, прошедшего проверку пароля.
+func (m *Manager) StartSession(user models.User, userAgent, ip string) (Tokens, error) {
+	id, err := randomToken(16, hex.EncodeToString)
+	if err != nil {
+		return Tokens{}, err
+	}
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := time.Now()
+	// заодно убираем истёкшие сеансы пользователя, чтобы таблица не росла
+	if err := m.db.Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&models.Session{}).Error; err != nil {
+		return Tokens{}, err
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.Session{
+		ID:               id,
+		UserID:           user.ID,
+		RefreshTokenHash: hashSecret(secret),
+		UserAgent:        userAgent,
+		IP:               ip,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(m.refreshTTL),
+	}
+	if err := m.db.Create(&session).Error; err != nil {
+		return Tokens{}, err
+	}
+	return m.issue(user, id, secret, now)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов того же сеанса.
+func (m *Manager) Refresh(refreshToken string) (Tokens, error) {
+	id, secret, ok := splitRefreshToken(refreshToken)
+	if !ok {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	var tokens Tokens
+	reused := false
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		// блокировка строки: два одновременных обновления одним токеном не пройдут оба
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		if session.RevokedAt != nil {
+			return ErrSessionRevoked
+		}
+		if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(session.RefreshTokenHash)) != 1 {
+			// предъявлен уже использованный токен: кто-то из двоих владельцев — посторонний
+			reused = true
+			return tx.Model(&session).UpdateColumn("revoked_at", now).Error
+		}
+		if now.After(session.ExpiresAt) {
+			return ErrRefreshTokenExpired
+		}
+
+		// роль и логин берём из БД, а не из прошлого токена
+		var user models.User
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionRevoked
+			}
+			return err
+		}
+
+		newSecret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&session).UpdateColumns(map[string]interface{}{
+			"refresh_token_hash": hashSecret(newSecret),
+			"last_used_at":       now,
+			"expires_at":         now.Add(m.refreshTTL),
+		}).Error; err != nil {
+			return err
+		}
+
+		tokens, err = m.issue(user, session.ID, newSecret, now)
+		return err
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+	if reused {
+		m.invalidate(func(sid string, _ Identity) bool { return sid == id })
+		return Tokens{}, ErrRefreshTokenReused
+	}
+	return tokens, nil
+}
+
+// Logout отзывает сеанс, которому принадлежит refresh-токен.
+// Повторный выход и выход из уже отозванного сеанса ошибкой не считаются.
+func (m *Manager) Logout(refreshToken string) error {
+	id, secret, ok := splitRefreshToken(refreshToken)
+	if !ok {
+		return ErrInvalidRefreshToken
+	}
+	err := m.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, hashSecret(secret)).
+		UpdateColumn("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	m.invalidate(func(sid string, _ Identity) bool { return sid == id })
+	return nil
+}
+
+// RevokeUserSessions отзывает все сеансы пользователя: уже выданные access-токены
+// перестают приниматься, refresh-токены — обмениваться. db может быть транзакцией;
+// после её коммита нужно вызвать InvalidateUser, иначе сеансы живут в кэше до cacheTTL.
+func (m *Manager) RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		UpdateColumn("revoked_at", time.Now()).Error
+}
+
+// InvalidateUser сбрасывает кэш сеансов пользователя. Вызывается после любого изменения
+// пользователя (роль, удаление, восстановление), чтобы следующий запрос перечитал его из БД.
+func (m *Manager) InvalidateUser(userID uint) {
+	m.invalidate(func(_ string, identity Identity) bool { return identity.UserID == userID })
+}
+
+// Authenticate проверяет подпись, алгоритм, kid, срок, iss и aud access-токена, то, что его сеанс не отозван,
+// а пользователь не удалён. Роль берётся из БД.
+func (m *Manager) Authenticate(tokenStr string) (Identity, error) {
+	claims, err := m.keys.Load().Parse(tokenStr)
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	uid, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+	// токены без sid выданы до появления сеансов — отозвать их нельзя, поэтому не принимаем
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		return Identity{}, ErrInvalidToken
+	}
+
+	identity, err := m.resolve(sid)
+	if err != nil {
+		return Identity{}, err
+	}
+	if identity.UserID != uint(uid) {
+		return Identity{}, ErrInvalidToken
+	}
+	return identity, nil
+}
+
+// resolve находит пользователя и его текущую роль по sid: из кэша или из БД.
+func (m *Manager) resolve(sid string) (Identity, error) {
+	now := time.Now()
+	var generation uint64
+	if m.cacheTTL > 0 {
+		m.mu.Lock()
+		cached, ok := m.cache[sid]
+		generation = m.generation
+		m.mu.Unlock()
+		if ok && now.Before(cached.expires) {
+			return cached.identity, nil
+		}
+	}
+
+	var session models.Session
+	if err := m.db.Select("id", "user_id", "revoked_at").First(&session, "id = ?", sid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Identity{}, ErrSessionRevoked
+		}
+		return Identity{}, err
+	}
+	if session.RevokedAt != nil {
+		return Identity{}, ErrSessionRevoked
+	}
+	// удалённые в корзину пользователи сюда не попадают
+	var user models.User
+	if err := m.db.Select("id", "role").First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Identity{}, ErrUserNotFound
+		}
+		return Identity{}, err
+	}
+	identity := Identity{UserID: user.ID, Role: user.Role, SessionID: sid}
+
+	if m.cacheTTL > 0 {
+		m.mu.Lock()
+		// заодно выбрасываем протухшие записи, чтобы кэш не рос бесконечно
+		if now.After(m.nextSweep) {
+			for k, v := range m.cache {
+				if now.After(v.expires) {
+					delete(m.cache, k)
+				}
+			}
+			m.nextSweep = now.Add(m.cacheTTL)
+		}
+		if generation == m.generation {
+			m.cache[sid] = cachedIdentity{identity: identity, expires: now.Add(m.cacheTTL)}
+		}
+		m.mu.Unlock()
+	}
+	return identity, nil
+}
+
+func (m *Manager) invalidate(match func(sid string, identity Identity) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	for sid, cached := range m.cache {
+		if match(sid, cached.identity) {
+			delete(m.cache, sid)
+		}
+	}
+}
+
+// issue подписывает access-токен и собирает refresh-токен сеанса.
+func (m *Manager) issue(user models.User, sid, secret string, now time.Time) (Tokens, error) {
+	claims := jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(user.ID), 10),
+		"login": user.Login,
+		"role":  user.Role,
+		"sid":   sid,
+		"iat":   now.Unix(),
+		"exp":   now.Add(m.accessTTL).Unix(),
+	}
+	signed, err := m.keys.Load().Sign(claims)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{
+		AccessToken:  signed,
+		RefreshToken: sid + "." + secret,
+		AccessTTL:    m.accessTTL,
+		RefreshTTL:   m.refreshTTL,
+	}, nil
+}
+
+func splitRefreshToken(token string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(token, ".")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+func randomToken(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
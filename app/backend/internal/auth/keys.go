@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ключи подписи access-токенов. Каждый ключ имеет идентификатор (kid), который пишется
+// в заголовок токена; проверка ищет ключ по kid и сверяет алгоритм токена с алгоритмом
+// ключа, поэтому подменить RS256 на HS256 с публичным ключом в роли секрета нельзя.
+// Ротация: новый ключ добавляется рядом со старым, токены старого ключа принимаются,
+// пока его файл лежит в каталоге.
+
+// SupportedAlgorithms алгоритмы, которые можно включить в JWT_ALGORITHMS.
+var SupportedAlgorithms = []string{"HS256", "RS256", "EdDSA"}
+
+// Config параметры подписи и проверки access-токенов.
+type Config struct {
+	// HMAC-секрет и его kid; используются, только если KeysDir не задан
+	Secret string
+	KeyID  string
+	// каталог с ключами: <kid>.pem (RSA или Ed25519, закрытый или только открытый) и <kid>.secret (HMAC)
+	KeysDir string
+	// kid ключа подписи; пусто — ключ с наибольшим kid среди закрытых ключей и секретов
+	SigningKeyID string
+	// допустимые алгоритмы токенов
+	Algorithms []string
+	Issuer     string
+	Audience   string
+}
+
+// Key ключ подписи или проверки.
+type Key struct {
+	ID        string
+	Algorithm string
+	// nil для ключа, от которого есть только открытая часть
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet неизменяемый набор ключей; при ротации целиком заменяется новым.
+type KeySet struct {
+	signing  *Key
+	keys     map[string]*Key
+	methods  []string
+	issuer   string
+	audience string
+}
+
+// LoadKeys читает ключи из конфигурации и проверяет их против списка алгоритмов.
+func LoadKeys(cfg Config) (*KeySet, error) {
+	allowed := make(map[string]bool)
+	for _, alg := range cfg.Algorithms {
+		if !isSupported(alg) {
+			return nil, fmt.Errorf("unsupported jwt algorithm %q", alg)
+		}
+		allowed[alg] = true
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("no jwt algorithms allowed")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
+	}
+
+	var keys []*Key
+	if cfg.KeysDir == "" {
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt secret is empty")
+		}
+		secret := []byte(cfg.Secret)
+		keys = append(keys, &Key{ID: cfg.KeyID, Algorithm: "HS256", signKey: secret, verifyKey: secret})
+	} else {
+		var err error
+		if keys, err = readKeysDir(cfg.KeysDir); err != nil {
+			return nil, err
+		}
+	}
+
+	ks := &KeySet{
+		keys:     make(map[string]*Key, len(keys)),
+		methods:  cfg.Algorithms,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("jwt key id is empty")
+		}
+		if !allowed[k.Algorithm] {
+			return nil, fmt.Errorf("jwt key %q uses %s, which is not in the allowed algorithms", k.ID, k.Algorithm)
+		}
+		ks.keys[k.ID] = k
+		if k.signKey == nil {
+			continue
+		}
+		if cfg.SigningKeyID == "" && (ks.signing == nil || k.ID > ks.signing.ID) {
+			ks.signing = k
+		}
+	}
+	if cfg.SigningKeyID != "" {
+		ks.signing = ks.keys[cfg.SigningKeyID]
+		if ks.signing == nil || ks.signing.signKey == nil {
+			return nil, fmt.Errorf("jwt signing key %q not found or has no private part", cfg.SigningKeyID)
+		}
+	}
+	if ks.signing == nil {
+		return nil, errors.New("no jwt signing key")
+	}
+	return ks, nil
+}
+
+// SigningKeyID kid ключа, которым подписываются новые токены.
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signing.ID
+}
+
+// Sign подписывает claims текущим ключом, добавляя iss и aud.
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = ks.issuer
+	claims["aud"] = ks.audience
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.signing.Algorithm), claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.signKey)
+}
+
+// Parse проверяет подпись, алгоритм, kid, срок, iss и aud токена.
+func (ks *KeySet) Parse(tokenStr string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("key %q does not accept %s", kid, t.Method.Alg())
+		}
+		return key.verifyKey, nil
+	},
+		jwt.WithValidMethods(ks.methods),
+		jwt.WithIssuer(ks.issuer),
+		jwt.WithAudience(ks.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// JWK открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS набор открытых ключей для проверки токенов другими сервисами.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части асимметричных ключей; HMAC-секреты не публикуются.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA", Kid: k.ID, Alg: k.Algorithm, Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP", Kid: k.ID, Alg: k.Algorithm, Use: "sig",
+				Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func readKeysDir(dir string) ([]*Key, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt keys dir: %w", err)
+	}
+	var keys []*Key
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(e.Name())
+		if ext != ".pem" && ext != ".secret" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read jwt key %s: %w", e.Name(), err)
+		}
+		kid := strings.TrimSuffix(e.Name(), ext)
+		var key *Key
+		if ext == ".secret" {
+			key, err = parseSecret(kid, data)
+		} else {
+			key, err = parsePEM(kid, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", e.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no jwt keys in %s", dir)
+	}
+	return keys, nil
+}
+
+func parseSecret(kid string, data []byte) (*Key, error) {
+	secret := []byte(strings.TrimSpace(string(data)))
+	// HS256 не сильнее своего секрета: меньше 256 бит не принимаем
+	if len(secret) < 32 {
+		return nil, errors.New("hmac secret must be at least 32 bytes")
+	}
+	return &Key{ID: kid, Algorithm: "HS256", signKey: secret, verifyKey: secret}, nil
+}
+
+func parsePEM(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.signKey = signer
+		parsed = signer.Public()
+	}
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		key.Algorithm = "RS256"
+		key.verifyKey = pub
+	case ed25519.PublicKey:
+		key.Algorithm = "EdDSA"
+		key.verifyKey = pub
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", pub)
+	}
+	return key, nil
+}
+
+func isSupported(alg string) bool {
+	for _, a := range SupportedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "buildefect"
+	testAudience = "buildefect-api"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+var allAlgorithms = []string{"HS256", "RS256", "EdDSA"}
+
+// testKeys ключи, разложенные по каталогу так же, как в JWT_KEYS_DIR.
+type testKeys struct {
+	dir    string
+	rsa    *rsa.PrivateKey
+	ed     ed25519.PrivateKey
+	rsaPEM []byte // открытая часть RSA-ключа, как её видит клиент из JWKS
+	oldRSA *rsa.PrivateKey
+	secret []byte
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pemBlock(t *testing.T, typ string, der []byte, err error) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+// newTestKeys создаёт каталог с RSA- и Ed25519-ключами, HMAC-секретом
+// и старым RSA-ключом, от которого осталась только открытая часть.
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	k := &testKeys{dir: t.TempDir(), secret: []byte(testSecret)}
+	var err error
+	if k.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if k.oldRSA, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if _, k.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.rsa)
+	writeFile(t, k.dir, "2024-rsa.pem", pemBlock(t, "PRIVATE KEY", der, err))
+	der, err = x509.MarshalPKCS8PrivateKey(k.ed)
+	writeFile(t, k.dir, "2025-ed.pem", pemBlock(t, "PRIVATE KEY", der, err))
+	der, err = x509.MarshalPKIXPublicKey(&k.oldRSA.PublicKey)
+	writeFile(t, k.dir, "2022-old.pem", pemBlock(t, "PUBLIC KEY", der, err))
+	writeFile(t, k.dir, "2023-hs.secret", append(k.secret, '\n'))
+	der, err = x509.MarshalPKIXPublicKey(&k.rsa.PublicKey)
+	k.rsaPEM = pemBlock(t, "PUBLIC KEY", der, err)
+	return k
+}
+
+func (k *testKeys) config() Config {
+	return Config{KeysDir: k.dir, Algorithms: allAlgorithms, Issuer: testIssuer, Audience: testAudience}
+}
+
+// validClaims claims, которые Parse должен принять.
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+// withClaim копия validClaims с изменённым (или удалённым при value == nil) полем.
+func withClaim(name string, value interface{}) jwt.MapClaims {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoadKeysDir(t *testing.T) {
+	k := newTestKeys(t)
+	tests := []struct {
+		name        string
+		signingKey  string
+		wantSigning string
+	}{
+		// закрытые ключи и секреты: 2023-hs, 2024-rsa, 2025-ed; 2022-old только открытый
+		{"по умолчанию наибольший kid", "", "2025-ed"},
+		{"задан явно", "2024-rsa", "2024-rsa"},
+		{"hmac-секрет", "2023-hs", "2023-hs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := k.config()
+			cfg.SigningKeyID = tt.signingKey
+			ks, err := LoadKeys(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ks.SigningKeyID(); got != tt.wantSigning {
+				t.Errorf("signing key = %q, want %q", got, tt.wantSigning)
+			}
+			token, err := ks.Sign(jwt.MapClaims{"sub": "1", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ks.Parse(token); err != nil {
+				t.Errorf("Parse of own token: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ks, err := LoadKeys(newTestKeys(t).config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kids []string
+	for _, key := range ks.JWKS().Keys {
+		kids = append(kids, key.Kid+":"+key.Alg)
+	}
+	// HMAC-секрет 2023-hs публиковаться не должен
+	want := "2022-old:RS256,2024-rsa:RS256,2025-ed:EdDSA"
+	if got := strings.Join(kids, ","); got != want {
+		t.Errorf("JWKS kids = %q, want %q", got, want)
+	}
+}
+
+func TestLoadKeysInvalid(t *testing.T) {
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakDER, err := x509.MarshalPKCS8PrivateKey(weakRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string // пусто — каталог с newTestKeys
+		edit  func(*Config)
+		want  string // фрагмент текста ошибки
+	}{
+		{
+			name:  "короткий секрет",
+			files: map[string]string{"k1.secret": strings.Repeat("x", 31)},
+			want:  "at least 32 bytes",
+		},
+		{
+			// пробелы и перевод строки вокруг секрета в длину не входят
+			name:  "короткий секрет с переводом строки",
+			files: map[string]string{"k1.secret": "  " + strings.Repeat("x", 31) + "\n"},
+			want:  "at least 32 bytes",
+		},
+		{
+			name:  "слабый rsa",
+			files: map[string]string{"k1.pem": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: weakDER}))},
+			want:  "at least 2048 bits",
+		},
+		{
+			name:  "не PEM",
+			files: map[string]string{"k1.pem": "not a key"},
+			want:  "no PEM block",
+		},
+		{
+			name:  "сертификат вместо ключа",
+			files: map[string]string{"k1.pem": "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"},
+			want:  `unsupported PEM block "CERTIFICATE"`,
+		},
+		{
+			name:  "нет ключей",
+			files: map[string]string{"README": "ключи лежат рядом"},
+			want:  "no jwt keys",
+		},
+		{
+			name: "неизвестный алгоритм",
+			edit: func(c *Config) { c.Algorithms = []string{"HS256", "HS512"} },
+			want: `unsupported jwt algorithm "HS512"`,
+		},
+		{
+			name: "none",
+			edit: func(c *Config) { c.Algorithms = []string{"none"} },
+			want: `unsupported jwt algorithm "none"`,
+		},
+		{
+			name: "нет алгоритмов",
+			edit: func(c *Config) { c.Algorithms = nil },
+			want: "no jwt algorithms allowed",
+		},
+		{
+			name: "алгоритм ключа не разрешён",
+			edit: func(c *Config) { c.Algorithms = []string{"RS256", "EdDSA"} },
+			want: `jwt key "2023-hs" uses HS256`,
+		},
+		{
+			name: "нет issuer",
+			edit: func(c *Config) { c.Issuer = "" },
+			want: "issuer and audience are required",
+		},
+		{
+			name: "нет audience",
+			edit: func(c *Config) { c.Audience = "" },
+			want: "issuer and audience are required",
+		},
+		{
+			name: "ключ подписи не найден",
+			edit: func(c *Config) { c.SigningKeyID = "2026-rsa" },
+			want: `jwt signing key "2026-rsa" not found`,
+		},
+		{
+			name: "ключ подписи без закрытой части",
+			edit: func(c *Config) { c.SigningKeyID = "2022-old" },
+			want: `jwt signing key "2022-old" not found or has no private part`,
+		},
+		{
+			name: "пустой секрет без каталога",
+			edit: func(c *Config) { c.KeysDir, c.Secret, c.KeyID = "", "", "default" },
+			want: "jwt secret is empty",
+		},
+		{
+			name: "секрет без kid",
+			edit: func(c *Config) { c.KeysDir, c.Secret, c.KeyID = "", testSecret, "" },
+			want: "jwt key id is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if tt.files == nil {
+				cfg = newTestKeys(t).config()
+			} else {
+				dir := t.TempDir()
+				for name, data := range tt.files {
+					writeFile(t, dir, name, []byte(data))
+				}
+				cfg = Config{KeysDir: dir, Algorithms: allAlgorithms, Issuer: testIssuer, Audience: testAudience}
+			}
+			if tt.edit != nil {
+				tt.edit(&cfg)
+			}
+			_, err := LoadKeys(cfg)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	k := newTestKeys(t)
+	ks, err := LoadKeys(k.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs256, hs256, eddsa := jwt.SigningMethodRS256, jwt.SigningMethodHS256, jwt.SigningMethodEdDSA
+	hour := time.Hour
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error // nil — токен принимается
+	}{
+		{"RS256", signToken(t, rs256, "2024-rsa", k.rsa, validClaims()), nil},
+		{"EdDSA", signToken(t, eddsa, "2025-ed", k.ed, validClaims()), nil},
+		{"HS256", signToken(t, hs256, "2023-hs", k.secret, validClaims()), nil},
+		{"aud списком", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("aud", []string{"other", testAudience})), nil},
+		{"в пределах leeway", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("exp", time.Now().Add(-10*time.Second).Unix())), nil},
+
+		// подмена алгоритма: открытый RSA-ключ из JWKS в роли HMAC-секрета
+		{"HS256 с открытым RSA-ключом", signToken(t, hs256, "2024-rsa", k.rsaPEM, validClaims()), jwt.ErrTokenUnverifiable},
+		{"HS256 с открытым ключом без kid", signToken(t, hs256, "", k.rsaPEM, validClaims()), jwt.ErrTokenUnverifiable},
+		{"RS256 с kid HMAC-секрета", signToken(t, rs256, "2023-hs", k.rsa, validClaims()), jwt.ErrTokenUnverifiable},
+		{"EdDSA с kid RSA-ключа", signToken(t, eddsa, "2024-rsa", k.ed, validClaims()), jwt.ErrTokenUnverifiable},
+		{"alg none", signToken(t, jwt.SigningMethodNone, "2024-rsa", jwt.UnsafeAllowNoneSignatureType, validClaims()), jwt.ErrTokenSignatureInvalid},
+		{"HS512 не из списка", signToken(t, jwt.SigningMethodHS512, "2023-hs", k.secret, validClaims()), jwt.ErrTokenSignatureInvalid},
+
+		{"неизвестный kid", signToken(t, rs256, "2026-rsa", k.rsa, validClaims()), jwt.ErrTokenUnverifiable},
+		{"нет kid", signToken(t, rs256, "", k.rsa, validClaims()), jwt.ErrTokenUnverifiable},
+		{"чужой ключ с известным kid", signToken(t, rs256, "2024-rsa", otherRSA, validClaims()), jwt.ErrTokenSignatureInvalid},
+		{"чужой ключ с kid старого ключа", signToken(t, rs256, "2022-old", otherRSA, validClaims()), jwt.ErrTokenSignatureInvalid},
+		{"испорченная подпись", signToken(t, rs256, "2024-rsa", k.rsa, validClaims()) + "A", jwt.ErrTokenSignatureInvalid},
+		{"не JWT", "not.a.token", jwt.ErrTokenMalformed},
+
+		{"чужой iss", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("iss", "other")), jwt.ErrTokenInvalidIssuer},
+		{"нет iss", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("iss", nil)), jwt.ErrTokenRequiredClaimMissing},
+		{"чужой aud", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("aud", "other")), jwt.ErrTokenInvalidAudience},
+		{"нет aud", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("aud", nil)), jwt.ErrTokenRequiredClaimMissing},
+		{"истёк", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("exp", time.Now().Add(-hour).Unix())), jwt.ErrTokenExpired},
+		{"нет exp", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("exp", nil)), jwt.ErrTokenRequiredClaimMissing},
+		{"выдан в будущем", signToken(t, rs256, "2024-rsa", k.rsa, withClaim("iat", time.Now().Add(hour).Unix())), jwt.ErrTokenUsedBeforeIssued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ks.Parse(tt.token)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if claims["sub"] != "1" {
+					t.Errorf("sub = %v, want %q", claims["sub"], "1")
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Токены ключа, выведенного из ротации (его файл удалён), больше не принимаются.
+func TestParseRotatedOut(t *testing.T) {
+	k := newTestKeys(t)
+	old, err := LoadKeys(k.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := old.Sign(validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(k.dir, "2025-ed.pem")); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := LoadKeys(k.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(token); !errors.Is(err, jwt.ErrTokenUnverifiable) {
+		t.Errorf("error = %v, want %v", err, jwt.ErrTokenUnverifiable)
+	}
+}
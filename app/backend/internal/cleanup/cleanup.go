@@ -0,0 +1,467 @@
+// Package cleanup сверяет хранилище вложений с таблицами defect_attachments,
+// comment_attachments и stored_files: находит файлы, на которые не ссылается ни одна
+// запись, записи вложений без дефекта, комментария или файла и неверные счётчики ссылок.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TableDefectAttachments  = "defect_attachments"
+	TableCommentAttachments = "comment_attachments"
+)
+
+// ключ advisory-блокировки Postgres: одновременно сверку выполняет только один процесс
+const advisoryLockKey = 7_210_422
+
+var (
+	// в БД нет ни одной записи о файлах, а в хранилище файлы есть: скорее всего, сервер
+	// смотрит не в ту БД (пустой стенд, неудачное восстановление, общий бакет)
+	ErrNoReferences = errors.New("no attachment or stored file rows in database, refusing to delete files")
+	// без записей оказалась слишком большая доля файлов хранилища
+	ErrTooManyOrphans = errors.New("too many orphaned files, refusing to delete")
+	// сверку уже выполняет другой процесс
+	ErrLocked = errors.New("storage cleanup is already running elsewhere")
+)
+
+// Options параметры прохода.
+type Options struct {
+	// только отчёт, ничего не удаляется и не исправляется
+	DryRun bool
+	// файлы моложе MinAge не трогаются: их может как раз сохранять незавершённая загрузка
+	MinAge time.Duration
+	// удалять записи вложений, файла которых нет в хранилище. По умолчанию такие записи
+	// только попадают в отчёт: иначе пустое хранилище (не тот бакет, не смонтирован
+	// каталог) стёрло бы все вложения
+	RemoveMissing bool
+	// наибольшая доля файлов хранилища без записей (0..1), при которой они ещё удаляются;
+	// больше — проход ничего не удаляет и возвращает ErrTooManyOrphans. 0 — без проверки
+	MaxOrphanShare float64
+}
+
+// Row запись вложения в отчёте.
+type Row struct {
+	Table string
+	ID    uint
+	Key   string
+}
+
+// Report результат прохода. При DryRun в нём то, что было бы удалено или исправлено.
+type Report struct {
+	DryRun bool
+	// файлы без записей, старше MinAge
+	OrphanFiles []storage.ObjectInfo
+	OrphanBytes int64
+	// сколько всего файлов в хранилище
+	ListedFiles int
+	// записи вложений, дефекта или комментария которых больше нет
+	DanglingRows []Row
+	// записи вложений, файла которых нет в хранилище (удаляются только с RemoveMissing)
+	MissingFiles []Row
+	// исправленные счётчики ссылок stored_files (в том числе удалённые записи без ссылок)
+	StoredFilesFixed int
+}
+
+// Log пишет отчёт: каждую найденную проблему и итог.
+func (r Report) Log(l zerolog.Logger) {
+	for _, o := range r.OrphanFiles {
+		l.Info().Str("key", o.Key).Int64("size", o.Size).Time("modified", o.ModTime).Bool("dry_run", r.DryRun).Msg("orphaned file")
+	}
+	for _, row := range r.DanglingRows {
+		l.Info().Str("table", row.Table).Uint("id", row.ID).Str("key", row.Key).Bool("dry_run", r.DryRun).Msg("attachment without defect or comment")
+	}
+	for _, row := range r.MissingFiles {
+		l.Warn().Str("table", row.Table).Uint("id", row.ID).Str("key", row.Key).Msg("attachment file is missing in storage")
+	}
+	l.Info().
+		Bool("dry_run", r.DryRun).
+		Int("listed_files", r.ListedFiles).
+		Int("orphan_files", len(r.OrphanFiles)).
+		Int64("orphan_bytes", r.OrphanBytes).
+		Int("dangling_rows", len(r.DanglingRows)).
+		Int("missing_files", len(r.MissingFiles)).
+		Int("stored_files_fixed", r.StoredFilesFixed).
+		Msg("storage cleanup finished")
+}
+
+// Collector выполняет сверку хранилища с БД.
+type Collector struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewCollector(db *gorm.DB, store storage.Storage) *Collector {
+	return &Collector{db: db, store: store}
+}
+
+// Start запускает сверку в фоне: сразу и затем каждые interval, пока не отменён ctx.
+// interval <= 0 — фоновая сверка выключена.
+func (c *Collector) Start(ctx context.Context, interval time.Duration, opts Options) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := c.Run(ctx, opts)
+			if errors.Is(err, ErrLocked) {
+				log.Debug().Msg("storage cleanup is running on another instance, skipping")
+			} else if err != nil {
+				log.Warn().Err(err).Msg("storage cleanup failed")
+				report.Log(log.Logger)
+			} else {
+				report.Log(log.Logger)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// attachmentRow поля вложения, нужные для сверки.
+type attachmentRow struct {
+	ID           uint
+	URL          string
+	ThumbnailKey string
+	PreviewKey   string
+}
+
+// Run выполняет один проход:
+//  1. удаляет записи вложений, дефект или комментарий которых удалён окончательно;
+//  2. обходит хранилище и удаляет файлы старше MinAge, на которые не ссылается ни одна запись;
+//  3. находит записи, файла которых нет в хранилище (и удаляет их с RemoveMissing);
+//  4. пересчитывает ссылки stored_files и удаляет файлы, на которые ссылок не осталось.
+//
+// На Postgres проход выполняется под advisory-блокировкой: если сверку уже ведёт другая
+// реплика или команда cleanup, Run сразу возвращает ErrLocked.
+func (c *Collector) Run(ctx context.Context, opts Options) (Report, error) {
+	if c.db.Dialector.Name() != "postgres" {
+		return c.run(ctx, opts)
+	}
+
+	var report Report
+	// блокировка сессионная, поэтому берётся и снимается на одном и том же соединении
+	err := c.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", advisoryLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return ErrLocked
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+
+		var err error
+		report, err = c.run(ctx, opts)
+		return err
+	})
+	return report, err
+}
+
+func (c *Collector) run(ctx context.Context, opts Options) (Report, error) {
+	report := Report{DryRun: opts.DryRun}
+	db := c.db.WithContext(ctx)
+
+	if err := c.removeDangling(db, opts, &report); err != nil {
+		return report, err
+	}
+
+	// записи читаются до обхода хранилища: файл вложения сохраняется раньше записи,
+	// поэтому у каждой прочитанной записи файл к началу обхода уже есть
+	var defectRows, commentRows []attachmentRow
+	if err := db.Model(&models.DefectAttachment{}).Select("id, url, thumbnail_key, preview_key").Scan(&defectRows).Error; err != nil {
+		return report, err
+	}
+	if err := db.Model(&models.CommentAttachment{}).Select("id, url").Scan(&commentRows).Error; err != nil {
+		return report, err
+	}
+	var storedKeys []string
+	if err := db.Model(&models.StoredFile{}).Pluck("key", &storedKeys).Error; err != nil {
+		return report, err
+	}
+
+	refs := map[string]bool{}
+	for _, r := range defectRows {
+		refs[r.URL], refs[r.ThumbnailKey], refs[r.PreviewKey] = true, true, true
+	}
+	for _, r := range commentRows {
+		refs[r.URL] = true
+	}
+	for _, k := range storedKeys {
+		for _, key := range fileKeys(k) {
+			refs[key] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	cutoff := time.Now().Add(-opts.MinAge)
+	err := c.store.List(ctx, "", func(o storage.ObjectInfo) error {
+		seen[o.Key] = true
+		report.ListedFiles++
+		if !refs[o.Key] && o.ModTime.Before(cutoff) {
+			report.OrphanFiles = append(report.OrphanFiles, o)
+			report.OrphanBytes += o.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	// защита от не той БД: без единой записи о файлах или при слишком большой доле
+	// «лишних» файлов ничего не удаляем, а отдаём отчёт с ошибкой
+	if len(report.OrphanFiles) > 0 && len(defectRows) == 0 && len(commentRows) == 0 && len(storedKeys) == 0 {
+		return report, ErrNoReferences
+	}
+	if opts.MaxOrphanShare > 0 && float64(len(report.OrphanFiles)) > opts.MaxOrphanShare*float64(report.ListedFiles) {
+		return report, fmt.Errorf("%w: %d of %d files, limit %.0f%%", ErrTooManyOrphans,
+			len(report.OrphanFiles), report.ListedFiles, opts.MaxOrphanShare*100)
+	}
+
+	// удаляем после обхода, чтобы не менять каталог во время листинга
+	if !opts.DryRun {
+		for _, o := range report.OrphanFiles {
+			if err := c.store.Delete(ctx, o.Key); err != nil {
+				log.Warn().Err(err).Str("key", o.Key).Msg("failed to delete orphaned file")
+			}
+		}
+	}
+
+	// записи без дефекта или комментария уже в отчёте (при DryRun они ещё не удалены)
+	dangling := map[Row]bool{}
+	for _, row := range report.DanglingRows {
+		dangling[row] = true
+	}
+	for _, r := range defectRows {
+		if row := (Row{Table: TableDefectAttachments, ID: r.ID, Key: r.URL}); !seen[r.URL] && !dangling[row] {
+			report.MissingFiles = append(report.MissingFiles, row)
+		}
+	}
+	for _, r := range commentRows {
+		if row := (Row{Table: TableCommentAttachments, ID: r.ID, Key: r.URL}); !seen[r.URL] && !dangling[row] {
+			report.MissingFiles = append(report.MissingFiles, row)
+		}
+	}
+	if opts.RemoveMissing && !opts.DryRun {
+		if err := c.removeMissing(ctx, db, report.MissingFiles); err != nil {
+			return report, err
+		}
+	}
+
+	fixed, err := c.reconcileStoredFiles(ctx, db, opts)
+	report.StoredFilesFixed = fixed
+	return report, err
+}
+
+// removeDangling удаляет записи вложений, дефект или комментарий которых удалён
+// окончательно (дефекты и комментарии из корзины не считаются удалёнными). Файлы таких
+// вложений без ссылок удаляются дальше в этом же проходе.
+func (c *Collector) removeDangling(db *gorm.DB, opts Options, report *Report) error {
+	defectIDs := db.Unscoped().Model(&models.Defect{}).Select("id")
+	var defectRows []models.DefectAttachment
+	if err := db.Where("defect_id NOT IN (?)", defectIDs).Find(&defectRows).Error; err != nil {
+		return err
+	}
+	commentIDs := db.Unscoped().Model(&models.Comment{}).Select("id")
+	var commentRows []models.CommentAttachment
+	if err := db.Where("comment_id NOT IN (?)", commentIDs).Find(&commentRows).Error; err != nil {
+		return err
+	}
+
+	for _, a := range defectRows {
+		report.DanglingRows = append(report.DanglingRows, Row{Table: TableDefectAttachments, ID: a.ID, Key: a.URL})
+	}
+	for _, a := range commentRows {
+		report.DanglingRows = append(report.DanglingRows, Row{Table: TableCommentAttachments, ID: a.ID, Key: a.URL})
+	}
+	if opts.DryRun {
+		return nil
+	}
+
+	for _, a := range defectRows {
+		if err := db.Delete(&models.DefectAttachment{}, a.ID).Error; err != nil {
+			return err
+		}
+	}
+	for _, a := range commentRows {
+		if err := db.Delete(&models.CommentAttachment{}, a.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMissing удаляет записи вложений без файла. Перед удалением отсутствие файла
+// проверяется ещё раз: запись могли удалить и создать заново с другим файлом.
+func (c *Collector) removeMissing(ctx context.Context, db *gorm.DB, rows []Row) error {
+	for _, row := range rows {
+		obj, err := c.store.Get(ctx, row.Key)
+		if err == nil {
+			obj.Body.Close()
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		switch row.Table {
+		case TableDefectAttachments:
+			var a models.DefectAttachment
+			if err := db.Clauses(clause.Returning{}).Where("id = ? AND url = ?", row.ID, row.Key).Delete(&a).Error; err != nil {
+				return err
+			}
+			// копии удаляются здесь только у вложений без хэша; общие — при пересчёте stored_files
+			if a.Hash == "" {
+				for _, k := range []string{a.ThumbnailKey, a.PreviewKey} {
+					if k != "" {
+						_ = c.store.Delete(ctx, k)
+					}
+				}
+			}
+		case TableCommentAttachments:
+			if err := db.Where("id = ? AND url = ?", row.ID, row.Key).Delete(&models.CommentAttachment{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileStoredFiles приводит ref_count в stored_files к числу вложений с этим хэшем.
+// Файлы без ссылок (в том числе записи с ref_count = 0, которые не успел удалить
+// обработчик) удаляются вместе с копиями. Счётчик пересчитывается, а файл удаляется под
+// блокировкой строки: загрузки и удаления меняют его в своих транзакциях под той же блокировкой.
+func (c *Collector) reconcileStoredFiles(ctx context.Context, db *gorm.DB, opts Options) (int, error) {
+	type hashCount struct {
+		Hash  string
+		Count int
+	}
+	counts := map[string]int{}
+	for _, model := range []any{&models.DefectAttachment{}, &models.CommentAttachment{}} {
+		var rows []hashCount
+		err := db.Model(model).Select("hash, COUNT(*) AS count").Where("hash <> ''").Group("hash").Scan(&rows).Error
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range rows {
+			counts[r.Hash] += r.Count
+		}
+	}
+
+	var stored []models.StoredFile
+	if err := db.Find(&stored).Error; err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	known := map[string]bool{}
+	for _, sf := range stored {
+		known[sf.Hash] = true
+		if counts[sf.Hash] == sf.RefCount && sf.RefCount > 0 {
+			continue
+		}
+		if opts.DryRun {
+			fixed++
+			continue
+		}
+
+		changed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var locked models.StoredFile
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "hash = ?", sf.Hash).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			n, err := countRefs(tx, sf.Hash)
+			if err != nil || (n == locked.RefCount && n > 0) {
+				return err
+			}
+			changed = true
+			if n == 0 {
+				// файл удаляется до записи: если удалить не удалось, запись останется до следующего прохода
+				for _, k := range fileKeys(locked.Key) {
+					if err := c.store.Delete(ctx, k); err != nil {
+						return err
+					}
+				}
+				return tx.Delete(&locked).Error
+			}
+			return tx.Model(&locked).Update("ref_count", n).Error
+		})
+		if err != nil {
+			return fixed, err
+		}
+		if changed {
+			fixed++
+		}
+	}
+
+	// вложения с хэшем, для которого нет записи stored_files: восстанавливаем запись,
+	// иначе файл удалится из хранилища вместе с первым же таким вложением
+	for hash := range counts {
+		if known[hash] {
+			continue
+		}
+		fixed++
+		if opts.DryRun {
+			continue
+		}
+		var a models.DefectAttachment
+		var sf models.StoredFile
+		if err := db.Where("hash = ?", hash).First(&a).Error; err == nil {
+			sf = models.StoredFile{Hash: hash, Key: a.URL, Size: a.Size, ContentType: a.ContentType}
+		} else {
+			var ca models.CommentAttachment
+			if err := db.Where("hash = ?", hash).First(&ca).Error; err != nil {
+				return fixed, err
+			}
+			sf = models.StoredFile{Hash: hash, Key: ca.URL, Size: ca.Size, ContentType: ca.ContentType}
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			n, err := countRefs(tx, hash)
+			if err != nil {
+				return err
+			}
+			sf.RefCount = n
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sf).Error
+		})
+		if err != nil {
+			return fixed, err
+		}
+	}
+	return fixed, nil
+}
+
+// countRefs число вложений дефектов и комментариев, ссылающихся на файл с хэшем hash.
+func countRefs(tx *gorm.DB, hash string) (int, error) {
+	var defects, comments int64
+	if err := tx.Model(&models.DefectAttachment{}).Where("hash = ?", hash).Count(&defects).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.CommentAttachment{}).Where("hash = ?", hash).Count(&comments).Error; err != nil {
+		return 0, err
+	}
+	return int(defects + comments), nil
+}
+
+// fileKeys ключ файла и ключи его уменьшенных копий.
+func fileKeys(key string) []string {
+	return []string{key, preview.Key(key, preview.Thumbnail), preview.Key(key, preview.Medium)}
+}
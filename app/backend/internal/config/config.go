@@ -1,23 +1,71 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/cleanup"
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
 )
 
+// секрет из примеров; с ним сервер стартует только в режиме разработки
+const defaultJWTSecret = "replace-this-secret"
+
 // Определяем структуру Config для хранения конфигурационных данных
 
 type Config struct {
+	// Режим работы (APP_ENV): development или production
+	Env string
+
 	DBHost     string
     DBPort     string
     DBUser     string
     DBPassword string
     DBName     string
 
-	JWTSecret string
+	// Ключи, алгоритмы, iss и aud access-токенов
+	JWT auth.Config
+
+	// Срок жизни access-токена и refresh-токена (сеанса без обращений)
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Сколько держать в памяти пользователя и роль сеанса (0 — каждый запрос в БД)
+	AuthCacheTTL time.Duration
+
+	// Путь к JSON-файлу со схемой переходов статусов дефекта (пусто — встроенная схема)
+	WorkflowFile string
+
+	// Сколько времени после создания автор может редактировать комментарий (0 — без ограничения)
+	CommentEditWindow time.Duration
+
+	// Хранилище файлов вложений: локальный каталог или S3-совместимый сервис
+	Storage storage.Config
+
+	// Ключ подписи и срок жизни ссылок на скачивание вложений
+	FileURLSecret string
+	FileURLTTL    time.Duration
+
+	// Ограничения на загружаемые вложения: размеры и разрешённые типы
+	Upload upload.Policy
+
+	// Сколько горутин строят миниатюры и превью фото
+	PreviewWorkers int
+
+	// Метаданные фото: удаление EXIF при отдаче и допустимое расстояние от здания
+	Photos exif.Options
+
+	// Фоновая сверка хранилища с БД (0 — выключена, по умолчанию) и её параметры
+	CleanupInterval time.Duration
+	Cleanup         cleanup.Options
 }
 
 func LoadConfig(l zerolog.Logger) *Config {
@@ -33,24 +81,183 @@ func LoadConfig(l zerolog.Logger) *Config {
 	cfg.DBUser = getEnv("POSTGRES_USER", "postgres")
 	cfg.DBPassword = getEnv("POSTGRES_PASSWORD", "postgres")
 	cfg.DBName = getEnv("POSTGRES_DB", "app")
-	cfg.JWTSecret = getEnv("JWT_SECRET", "replace-this-secret")
+	cfg.Env = getEnv("APP_ENV", "production")
+	cfg.JWT = auth.Config{
+		Secret:       getEnv("JWT_SECRET", defaultJWTSecret),
+		KeyID:        getEnv("JWT_KEY_ID", "default"),
+		KeysDir:      getEnv("JWT_KEYS_DIR", ""),
+		SigningKeyID: getEnv("JWT_SIGNING_KEY_ID", ""),
+		Issuer:       getEnv("JWT_ISSUER", "buildefect"),
+		Audience:     getEnv("JWT_AUDIENCE", "buildefect-api"),
+	}
+	for _, alg := range strings.Split(getEnv("JWT_ALGORITHMS", strings.Join(auth.SupportedAlgorithms, ",")), ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			cfg.JWT.Algorithms = append(cfg.JWT.Algorithms, alg)
+		}
+	}
+	cfg.WorkflowFile = getEnv("DEFECT_WORKFLOW_FILE", "")
+
+	accessTTL, err := time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m"))
+	if err != nil || accessTTL <= 0 {
+		l.Warn().Str("ACCESS_TOKEN_TTL", os.Getenv("ACCESS_TOKEN_TTL")).Msg("invalid access token ttl, using 15m")
+		accessTTL = 15 * time.Minute
+	}
+	cfg.AccessTokenTTL = accessTTL
+	refreshTTL, err := time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
+	if err != nil || refreshTTL <= 0 {
+		l.Warn().Str("REFRESH_TOKEN_TTL", os.Getenv("REFRESH_TOKEN_TTL")).Msg("invalid refresh token ttl, using 720h")
+		refreshTTL = 720 * time.Hour
+	}
+	cfg.RefreshTokenTTL = refreshTTL
+	authCacheTTL, err := time.ParseDuration(getEnv("AUTH_CACHE_TTL", "30s"))
+	if err != nil || authCacheTTL < 0 {
+		l.Warn().Str("AUTH_CACHE_TTL", os.Getenv("AUTH_CACHE_TTL")).Msg("invalid auth cache ttl, using 30s")
+		authCacheTTL = 30 * time.Second
+	}
+	cfg.AuthCacheTTL = authCacheTTL
+
+	editWindow, err := time.ParseDuration(getEnv("COMMENT_EDIT_WINDOW", "15m"))
+	if err != nil || editWindow < 0 {
+		l.Warn().Str("COMMENT_EDIT_WINDOW", os.Getenv("COMMENT_EDIT_WINDOW")).Msg("invalid comment edit window, using 15m")
+		editWindow = 15 * time.Minute
+	}
+	cfg.CommentEditWindow = editWindow
+
+	cfg.Storage = storage.Config{
+		Driver:      getEnv("STORAGE_DRIVER", "local"),
+		LocalRoot:   getEnv("STORAGE_LOCAL_ROOT", "internal/uploads"),
+		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+		S3Region:    getEnv("S3_REGION", ""),
+		S3Bucket:    getEnv("S3_BUCKET", "buildefect"),
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+	}
+	useSSL, err := strconv.ParseBool(getEnv("S3_USE_SSL", "true"))
+	if err != nil {
+		l.Warn().Str("S3_USE_SSL", os.Getenv("S3_USE_SSL")).Msg("invalid S3_USE_SSL, using true")
+		useSSL = true
+	}
+	cfg.Storage.S3UseSSL = useSSL
+
+	// по умолчанию ссылки подписываются тем же секретом, что и JWT
+	cfg.FileURLSecret = getEnv("FILE_URL_SECRET", cfg.JWT.Secret)
+	fileURLTTL, err := time.ParseDuration(getEnv("FILE_URL_TTL", "15m"))
+	if err != nil || fileURLTTL <= 0 {
+		l.Warn().Str("FILE_URL_TTL", os.Getenv("FILE_URL_TTL")).Msg("invalid file url ttl, using 15m")
+		fileURLTTL = 15 * time.Minute
+	}
+	cfg.FileURLTTL = fileURLTTL
+
+	cfg.Upload = upload.Policy{
+		MaxImageSize: getSizeMB(l, "UPLOAD_MAX_IMAGE_MB", 20),
+		MaxFileSize:  getSizeMB(l, "UPLOAD_MAX_FILE_MB", 50),
+		AllowedTypes: upload.DefaultAllowedTypes,
+	}
+	if v := getEnv("UPLOAD_ALLOWED_TYPES", ""); v != "" {
+		cfg.Upload.AllowedTypes = nil
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				cfg.Upload.AllowedTypes = append(cfg.Upload.AllowedTypes, t)
+			}
+		}
+	}
+
+	previewWorkers, err := strconv.Atoi(getEnv("PREVIEW_WORKERS", "2"))
+	if err != nil || previewWorkers < 1 {
+		l.Warn().Str("PREVIEW_WORKERS", os.Getenv("PREVIEW_WORKERS")).Msg("invalid preview workers count, using 2")
+		previewWorkers = 2
+	}
+	cfg.PreviewWorkers = previewWorkers
+
+	stripExif, err := strconv.ParseBool(getEnv("EXIF_STRIP", "false"))
+	if err != nil {
+		l.Warn().Str("EXIF_STRIP", os.Getenv("EXIF_STRIP")).Msg("invalid EXIF_STRIP, using false")
+		stripExif = false
+	}
+	maxDistance, err := strconv.ParseFloat(getEnv("PHOTO_MAX_DISTANCE", "500"), 64)
+	if err != nil || maxDistance <= 0 {
+		l.Warn().Str("PHOTO_MAX_DISTANCE", os.Getenv("PHOTO_MAX_DISTANCE")).Msg("invalid photo max distance, using 500 m")
+		maxDistance = 500
+	}
+	cfg.Photos = exif.Options{Strip: stripExif, MaxDistance: maxDistance}
+
+	// фоновая сверка удаляет файлы, поэтому включается явно и только на одном экземпляре
+	cleanupInterval, err := time.ParseDuration(getEnv("CLEANUP_INTERVAL", "0"))
+	if err != nil || cleanupInterval < 0 {
+		l.Warn().Str("CLEANUP_INTERVAL", os.Getenv("CLEANUP_INTERVAL")).Msg("invalid cleanup interval, background cleanup disabled")
+		cleanupInterval = 0
+	}
+	cfg.CleanupInterval = cleanupInterval
+	cleanupMinAge, err := time.ParseDuration(getEnv("CLEANUP_MIN_AGE", "24h"))
+	if err != nil || cleanupMinAge < 0 {
+		l.Warn().Str("CLEANUP_MIN_AGE", os.Getenv("CLEANUP_MIN_AGE")).Msg("invalid cleanup min age, using 24h")
+		cleanupMinAge = 24 * time.Hour
+	}
+	cleanupDryRun, err := strconv.ParseBool(getEnv("CLEANUP_DRY_RUN", "true"))
+	if err != nil {
+		l.Warn().Str("CLEANUP_DRY_RUN", os.Getenv("CLEANUP_DRY_RUN")).Msg("invalid CLEANUP_DRY_RUN, using true")
+		cleanupDryRun = true
+	}
+	removeMissing, err := strconv.ParseBool(getEnv("CLEANUP_REMOVE_MISSING", "false"))
+	if err != nil {
+		l.Warn().Str("CLEANUP_REMOVE_MISSING", os.Getenv("CLEANUP_REMOVE_MISSING")).Msg("invalid CLEANUP_REMOVE_MISSING, using false")
+		removeMissing = false
+	}
+	maxOrphanShare, err := strconv.ParseFloat(getEnv("CLEANUP_MAX_ORPHAN_SHARE", "0.2"), 64)
+	if err != nil || maxOrphanShare < 0 || maxOrphanShare > 1 {
+		l.Warn().Str("CLEANUP_MAX_ORPHAN_SHARE", os.Getenv("CLEANUP_MAX_ORPHAN_SHARE")).Msg("invalid CLEANUP_MAX_ORPHAN_SHARE, using 0.2")
+		maxOrphanShare = 0.2
+	}
+	cfg.Cleanup = cleanup.Options{DryRun: cleanupDryRun, MinAge: cleanupMinAge, RemoveMissing: removeMissing, MaxOrphanShare: maxOrphanShare}
 
 	// Для запуска через Docker
 	if getEnv("IS_DOCKER", "") == "true" {
 		cfg.DBHost = getEnv("POSTGRES_HOST", "postgres")
 	}
 
+	l.Trace().Str("env", cfg.Env).Msg("App config")
 	l.Trace().Str("DBHost", cfg.DBHost).Str("DBPort", cfg.DBPort).Msg("Postgres config")
+	l.Trace().Str("driver", cfg.Storage.Driver).Str("S3Endpoint", cfg.Storage.S3Endpoint).Msg("Storage config")
 
 	// Возвращаем экземпляр конфига
 	return cfg
 }
 
+// IsDevelopment — режим разработки (APP_ENV=development).
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "development"
+}
+
+// Validate проверяет настройки безопасности: вне режима разработки секрет по умолчанию
+// не годится ни для JWT, ни для подписи ссылок на файлы.
+func (c *Config) Validate() error {
+	if c.IsDevelopment() {
+		return nil
+	}
+	if c.JWT.KeysDir == "" && c.JWT.Secret == defaultJWTSecret {
+		return errors.New("JWT_SECRET is not set: set JWT_SECRET or JWT_KEYS_DIR, or APP_ENV=development for local runs")
+	}
+	if c.FileURLSecret == defaultJWTSecret {
+		return errors.New("FILE_URL_SECRET is not set: set FILE_URL_SECRET or JWT_SECRET")
+	}
+	return nil
+}
+
 func (c *Config) DBConnString() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
 }
 
+// getSizeMB читает из env размер в мегабайтах и возвращает его в байтах
+func getSizeMB(l zerolog.Logger, key string, defaultMB int64) int64 {
+	mb, err := strconv.ParseInt(getEnv(key, strconv.FormatInt(defaultMB, 10)), 10, 64)
+	if err != nil || mb <= 0 {
+		l.Warn().Str(key, os.Getenv(key)).Msgf("invalid size, using %d MB", defaultMB)
+		mb = defaultMB
+	}
+	return mb << 20
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
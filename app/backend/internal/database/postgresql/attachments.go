@@ -0,0 +1,27 @@
+package postgresql
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// До появления internal/storage в url вложений лежал путь на диске
+// ("internal/uploads/defect_attachments/..."). Теперь там ключ хранилища
+// относительно его корня ("defect_attachments/..."), старые записи приводим к нему.
+const legacyUploadsPrefix = "internal/uploads/"
+
+var attachmentKeyMigrations = []string{
+	`UPDATE defect_attachments SET url = substr(url, ?) WHERE url LIKE ?`,
+	`UPDATE comment_attachments SET url = substr(url, ?) WHERE url LIKE ?`,
+}
+
+// migrateAttachmentKeys переводит url старых вложений в ключи хранилища (идемпотентно).
+func migrateAttachmentKeys(db *gorm.DB) error {
+	for _, stmt := range attachmentKeyMigrations {
+		if err := db.Exec(stmt, len(legacyUploadsPrefix)+1, legacyUploadsPrefix+"%").Error; err != nil {
+			return fmt.Errorf("attachment key migration failed: %w", err)
+		}
+	}
+	return nil
+}
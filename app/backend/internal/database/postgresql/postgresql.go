@@ -56,12 +56,26 @@ func Connect(cfg *config.Config, l zerolog.Logger) (*Database, error) {
 		&models.Building{},
 		&models.Comment{},
 		&models.CommentAttachment{},
+		&models.CommentRevision{},
+		&models.CommentMention{},
 		&models.Defect{},
 		&models.DefectAttachment{},
+		&models.DefectHistory{},
+		&models.DefectStatusPeriod{},
+		&models.StoredFile{},
+		&models.Session{},
 	); err != nil {
 		l.Error().Err(err).Msg("auto-migrate failed")
 		return nil, fmt.Errorf("auto-migrate failed: %w", err)
 	}
+	if err := migrateSearch(gormDB); err != nil {
+		l.Error().Err(err).Msg("search migration failed")
+		return nil, err
+	}
+	if err := migrateAttachmentKeys(gormDB); err != nil {
+		l.Error().Err(err).Msg("attachment key migration failed")
+		return nil, err
+	}
 	l.Info().Msg("auto-migrate completed")
 
 
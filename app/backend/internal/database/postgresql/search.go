@@ -0,0 +1,43 @@
+package postgresql
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Полнотекстовый поиск. У defects, comments и buildings есть сгенерированная колонка
+// search_vector (tsvector) с GIN-индексом: PostgreSQL сам пересчитывает её при каждом
+// INSERT/UPDATE, поэтому индексы всегда актуальны без триггеров и фоновых задач.
+//
+// Конфигурация russian стеммит кириллицу русским словарём, а латиницу (asciiword) —
+// английским, так что один tsvector покрывает оба языка.
+// Веса: A — заголовок дефекта, B — описание, C — комментарии, D — здание.
+// Колонки не описаны в моделях GORM, AutoMigrate их не трогает.
+var searchMigrations = []string{
+	`ALTER TABLE defects ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_defects_search_vector ON defects USING GIN (search_vector)`,
+
+	`ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(text, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING GIN (search_vector)`,
+
+	`ALTER TABLE buildings ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(address, '')), 'D')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_buildings_search_vector ON buildings USING GIN (search_vector)`,
+}
+
+// migrateSearch создаёт колонки и индексы полнотекстового поиска (идемпотентно).
+func migrateSearch(db *gorm.DB) error {
+	for _, stmt := range searchMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("search migration failed: %w", err)
+		}
+	}
+	return nil
+}
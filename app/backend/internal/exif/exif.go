@@ -0,0 +1,250 @@
+package exif
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Чтение метаданных EXIF из фото с телефонов: время съёмки, устройство, ориентация
+// и координаты GPS. Поддерживаются JPEG (сегмент APP1) и HEIC (элемент "Exif" в meta).
+
+var ErrNoExif = errors.New("no exif metadata")
+
+// Metadata данные EXIF, которые сохраняются у вложения. Отсутствующие поля пустые.
+type Metadata struct {
+	TakenAt *time.Time
+	Make    string
+	Model   string
+	// 1..8 по спецификации EXIF, 0 — не указана
+	Orientation int
+	Latitude    *float64
+	Longitude   *float64
+}
+
+// Device производитель и модель одной строкой ("Apple iPhone 14 Pro"). Многие камеры
+// повторяют производителя в модели ("Canon" + "Canon EOS 5D"), тогда он не дублируется.
+func (m Metadata) Device() string {
+	if m.Make == "" || strings.HasPrefix(strings.ToLower(m.Model), strings.ToLower(m.Make)) {
+		return m.Model
+	}
+	if m.Model == "" {
+		return m.Make
+	}
+	return m.Make + " " + m.Model
+}
+
+// Supported можно ли прочитать EXIF из файла этого типа.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/heic", "image/heif":
+		return true
+	}
+	return false
+}
+
+// Read читает EXIF файла типа contentType (image/jpeg, image/heic или image/heif).
+// Для других типов и фото без EXIF возвращает ErrNoExif.
+func Read(r io.ReaderAt, size int64, contentType string) (Metadata, error) {
+	var tiff []byte
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		tiff, err = jpegExif(io.NewSectionReader(r, 0, size))
+	case "image/heic", "image/heif":
+		var off, n int64
+		off, n, err = heicExifRange(r, size)
+		if err == nil {
+			tiff, err = heicTIFF(r, off, n)
+		}
+	default:
+		return Metadata{}, ErrNoExif
+	}
+	if err != nil {
+		return Metadata{}, err
+	}
+	return parseTIFF(tiff)
+}
+
+// теги EXIF
+const (
+	tagMake               = 0x010F
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef  = 1
+	tagGPSLatitude     = 2
+	tagGPSLongitudeRef = 3
+	tagGPSLongitude    = 4
+)
+
+// размеры значений по типам TIFF (1 BYTE, 2 ASCII, 3 SHORT, 4 LONG, 5 RATIONAL, 7 UNDEFINED,
+// 9 SLONG, 10 SRATIONAL)
+var typeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func parseTIFF(data []byte) (Metadata, error) {
+	var m Metadata
+	if len(data) < 8 {
+		return m, ErrNoExif
+	}
+	t := tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return m, ErrNoExif
+	}
+	if t.order.Uint16(data[2:4]) != 42 {
+		return m, ErrNoExif
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return m, err
+	}
+	m.Make = t.ascii(ifd0[tagMake])
+	m.Model = t.ascii(ifd0[tagModel])
+	if o := t.uint(ifd0[tagOrientation]); o >= 1 && o <= 8 {
+		m.Orientation = int(o)
+	}
+
+	// время съёмки из Exif IFD, запасной вариант — время изменения из IFD0
+	taken, offset := t.ascii(ifd0[tagDateTime]), ""
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if sub, err := t.readIFD(t.uint(e)); err == nil {
+			if s := t.ascii(sub[tagDateTimeOriginal]); s != "" {
+				taken = s
+			}
+			offset = t.ascii(sub[tagOffsetTimeOriginal])
+		}
+	}
+	m.TakenAt = parseTime(taken, offset)
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(t.uint(e)); err == nil {
+			lat, latOK := t.degrees(gps[tagGPSLatitude])
+			lon, lonOK := t.degrees(gps[tagGPSLongitude])
+			if latOK && lonOK && (lat != 0 || lon != 0) {
+				if t.ascii(gps[tagGPSLatitudeRef]) == "S" {
+					lat = -lat
+				}
+				if t.ascii(gps[tagGPSLongitudeRef]) == "W" {
+					lon = -lon
+				}
+				m.Latitude, m.Longitude = &lat, &lon
+			}
+		}
+	}
+	return m, nil
+}
+
+func (t tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil, fmt.Errorf("exif: ifd offset %d out of range", offset)
+	}
+	n := int(t.order.Uint16(t.data[offset:]))
+	entries := make(map[uint16]ifdEntry, n)
+	p := int64(offset) + 2
+	for i := 0; i < n; i++ {
+		if p+12 > int64(len(t.data)) {
+			break
+		}
+		e := t.data[p : p+12]
+		p += 12
+
+		tag, typ, count := t.order.Uint16(e[0:2]), t.order.Uint16(e[2:4]), t.order.Uint32(e[4:8])
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		total := int64(size) * int64(count)
+		value := e[8:12]
+		if total > 4 {
+			off := int64(t.order.Uint32(e[8:12]))
+			if off+total > int64(len(t.data)) {
+				continue
+			}
+			value = t.data[off : off+total]
+		} else {
+			value = value[:total]
+		}
+		entries[tag] = ifdEntry{typ: typ, count: count, value: value}
+	}
+	return entries, nil
+}
+
+func (t tiffReader) ascii(e ifdEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	s := string(e.value)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func (t tiffReader) uint(e ifdEntry) uint32 {
+	switch {
+	case e.typ == 3 && len(e.value) >= 2:
+		return uint32(t.order.Uint16(e.value))
+	case e.typ == 4 && len(e.value) >= 4:
+		return t.order.Uint32(e.value)
+	}
+	return 0
+}
+
+// degrees переводит тройку RATIONAL (градусы, минуты, секунды) в десятичные градусы.
+func (t tiffReader) degrees(e ifdEntry) (float64, bool) {
+	if e.typ != 5 || e.count < 3 || len(e.value) < 24 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num, den := t.order.Uint32(e.value[i*8:]), t.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
+
+// parseTime разбирает "2006:01:02 15:04:05" со смещением "+03:00". Без смещения время
+// считается UTC: камера не знает часовой пояс.
+func parseTime(s, offset string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", s+offset); err == nil {
+			return &t
+		}
+	}
+	t, err := time.Parse("2006:01:02 15:04:05", s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,415 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// Образцы фото с телефонов — см. testdata/README.md.
+var phoneSamples = []struct {
+	file        string
+	device      string
+	orientation int
+	takenAt     time.Time
+	lat, lon    float64
+}{
+	{"iphone-4s.jpg", "Apple iPhone 4S", 6, time.Date(2014, 9, 1, 15, 3, 47, 0, time.UTC), 59.33255, 18.06494},
+	{"htc-adr6400l.jpg", "HTC ADR6400L", 0, time.Date(2012, 12, 19, 21, 38, 40, 0, time.UTC), 40.77034, -111.89122},
+}
+
+func readSample(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func readBytes(data []byte, contentType string) (Metadata, error) {
+	return Read(bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// sampleTIFF TIFF-данные EXIF из образца JPEG.
+func sampleTIFF(t testing.TB, name string) []byte {
+	t.Helper()
+	tiff, err := jpegExif(bytes.NewReader(readSample(t, name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tiff
+}
+
+func checkMetadata(t *testing.T, m Metadata, device string, orientation int, takenAt time.Time, lat, lon float64) {
+	t.Helper()
+	if got := m.Device(); got != device {
+		t.Errorf("device = %q, want %q", got, device)
+	}
+	if m.Orientation != orientation {
+		t.Errorf("orientation = %d, want %d", m.Orientation, orientation)
+	}
+	if m.TakenAt == nil || !m.TakenAt.Equal(takenAt) {
+		t.Errorf("taken at = %v, want %v", m.TakenAt, takenAt)
+	}
+	if m.Latitude == nil || m.Longitude == nil {
+		t.Fatalf("no coordinates")
+	}
+	if math.Abs(*m.Latitude-lat) > 1e-4 || math.Abs(*m.Longitude-lon) > 1e-4 {
+		t.Errorf("coordinates = %f, %f, want %f, %f", *m.Latitude, *m.Longitude, lat, lon)
+	}
+}
+
+func TestReadPhoneJPEG(t *testing.T) {
+	for _, s := range phoneSamples {
+		t.Run(s.file, func(t *testing.T) {
+			m, err := readBytes(readSample(t, s.file), "image/jpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkMetadata(t, m, s.device, s.orientation, s.takenAt, s.lat, s.lon)
+		})
+	}
+}
+
+func TestReadPhoneHEIC(t *testing.T) {
+	for _, s := range phoneSamples {
+		for _, version := range []byte{2, 3} {
+			t.Run(s.file, func(t *testing.T) {
+				data := heicWithExif(sampleTIFF(t, s.file), version)
+				m, err := readBytes(data, "image/heic")
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkMetadata(t, m, s.device, s.orientation, s.takenAt, s.lat, s.lon)
+			})
+		}
+	}
+}
+
+func TestReadOffsetTime(t *testing.T) {
+	m, err := parseTIFF(buildTIFF(binary.LittleEndian, []tiffEntry{
+		{tagExifIFD, 4, 1, nil},
+	}, []tiffEntry{
+		{tagDateTimeOriginal, 2, 20, []byte("2025:10:07 14:03:12\x00")},
+		{tagOffsetTimeOriginal, 2, 7, []byte("+03:00\x00")},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, 10, 7, 11, 3, 12, 0, time.UTC)
+	if m.TakenAt == nil || !m.TakenAt.Equal(want) {
+		t.Errorf("taken at = %v, want %v", m.TakenAt, want)
+	}
+}
+
+func TestReadNoExif(t *testing.T) {
+	// JPEG без APP1: SOI, APP0 (JFIF), SOS
+	jfif := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0, 0xFF, 0xDA}
+	if _, err := readBytes(jfif, "image/jpeg"); !errors.Is(err, ErrNoExif) {
+		t.Errorf("jpeg without exif: err = %v, want ErrNoExif", err)
+	}
+	if _, err := readBytes(readSample(t, "iphone-4s.jpg"), "image/png"); !errors.Is(err, ErrNoExif) {
+		t.Errorf("unsupported type: err = %v, want ErrNoExif", err)
+	}
+	ftyp := box8("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	if _, err := readBytes(ftyp, "image/heic"); !errors.Is(err, ErrNoExif) {
+		t.Errorf("heic without meta: err = %v, want ErrNoExif", err)
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	for _, s := range phoneSamples {
+		jpg := readSample(t, s.file)
+		heic := heicWithExif(sampleTIFF(t, s.file), 2)
+		for _, tc := range []struct {
+			contentType string
+			data        []byte
+		}{{"image/jpeg", jpg}, {"image/heic", heic}} {
+			// обрезанный файл не должен ронять разбор; до конца EXIF — ошибка или пустые поля
+			for n := 0; n < len(tc.data); n += 1 + n/64 {
+				m, err := readBytes(tc.data[:n], tc.contentType)
+				if n < 16 && err == nil {
+					t.Errorf("%s %s cut at %d: no error", s.file, tc.contentType, n)
+				}
+				if err == nil && m.Device() != "" && m.Device() != s.device {
+					t.Errorf("%s %s cut at %d: device = %q", s.file, tc.contentType, n, m.Device())
+				}
+				_ = StripJPEG(io.Discard, bytes.NewReader(tc.data[:n]))
+				_ = StripHEIC(append([]byte(nil), tc.data[:n]...))
+			}
+		}
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	app1 := func(payload []byte) []byte {
+		seg := []byte{0xFF, 0xD8, 0xFF, markerAPP1}
+		seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+		return append(append(seg, payload...), 0xFF, markerSOS)
+	}
+	exifAPP1 := func(tiff []byte) []byte {
+		return app1(append(append([]byte{}, exifHeader...), tiff...))
+	}
+	hugeBox := binary.BigEndian.AppendUint32(nil, 1)
+	hugeBox = append(hugeBox, "meta"...)
+	hugeBox = binary.BigEndian.AppendUint64(hugeBox, math.MaxUint64-4)
+
+	cases := []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"empty jpeg", "image/jpeg", nil},
+		{"not a jpeg", "image/jpeg", []byte("GIF89a")},
+		{"bad marker", "image/jpeg", []byte{0xFF, 0xD8, 0x00, 0xE1}},
+		{"segment length below 2", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}},
+		{"segment longer than file", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}},
+		{"bad tiff magic", "image/jpeg", exifAPP1([]byte("II\x2B\x00\x08\x00\x00\x00"))},
+		{"unknown byte order", "image/jpeg", exifAPP1([]byte("XX\x00\x2A\x00\x00\x00\x08"))},
+		{"ifd offset out of range", "image/jpeg", exifAPP1([]byte("MM\x00\x2A\xFF\xFF\xFF\xF0"))},
+		{"empty heic", "image/heic", nil},
+		{"heic box past end", "image/heic", []byte{0, 0, 0, 0x40, 'f', 't', 'y', 'p'}},
+		{"heic 64-bit box size overflow", "image/heic", hugeBox},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readBytes(tc.data, tc.contentType); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestReadBrokenEntries(t *testing.T) {
+	// значения за пределами данных и GPS с нулевым знаменателем пропускаются, остальное читается
+	rational := func(vals ...uint32) []byte {
+		var b []byte
+		for _, v := range vals {
+			b = binary.BigEndian.AppendUint32(b, v)
+		}
+		return b
+	}
+	tiff := buildTIFF(binary.BigEndian, []tiffEntry{
+		{tagMake, 2, 6, []byte("Apple\x00")},
+		{tagModel, 2, 1000, nil}, // значение указывает за конец данных
+		{tagOrientation, 3, 1, binary.BigEndian.AppendUint16(nil, 9)},
+		{tagGPSIFD, 4, 1, nil},
+	}, []tiffEntry{
+		{tagGPSLatitude, 5, 3, rational(59, 1, 56, 0, 0, 1)},
+		{tagGPSLongitude, 5, 3, rational(30, 1, 19, 1, 0, 1)},
+	})
+	m, err := parseTIFF(tiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Make != "Apple" || m.Model != "" {
+		t.Errorf("make, model = %q, %q", m.Make, m.Model)
+	}
+	if m.Orientation != 0 {
+		t.Errorf("orientation = %d, want 0 for out-of-range value", m.Orientation)
+	}
+	if m.Latitude != nil || m.Longitude != nil {
+		t.Errorf("coordinates with zero denominator: %v, %v", *m.Latitude, *m.Longitude)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	for _, s := range phoneSamples {
+		t.Run(s.file, func(t *testing.T) {
+			src := readSample(t, s.file)
+			if _, ok := scanStart(src); !ok {
+				// в образце только заголовки: собираем целый JPEG с тем же EXIF
+				src = jpegWithExif(t, sampleTIFF(t, s.file))
+			}
+			var dst bytes.Buffer
+			if err := StripJPEG(&dst, bytes.NewReader(src)); err != nil {
+				t.Fatal(err)
+			}
+			m, err := readBytes(dst.Bytes(), "image/jpeg")
+			if s.orientation > 1 {
+				// остаётся только ориентация
+				if err != nil {
+					t.Fatal(err)
+				}
+				if m.Orientation != s.orientation || m.Device() != "" || m.TakenAt != nil || m.Latitude != nil {
+					t.Errorf("stripped metadata = %+v", m)
+				}
+			} else if !errors.Is(err, ErrNoExif) {
+				t.Errorf("err = %v, want ErrNoExif", err)
+			}
+			if orig, _ := readBytes(src, "image/jpeg"); bytes.Contains(dst.Bytes(), []byte(orig.Model)) {
+				t.Errorf("device model left in stripped file")
+			}
+			if _, err := decodeJPEG(dst.Bytes()); err != nil {
+				t.Errorf("stripped file does not decode: %v", err)
+			}
+			// данные изображения начиная с SOS не меняются
+			start, _ := scanStart(src)
+			if !bytes.HasSuffix(dst.Bytes(), src[start:]) {
+				t.Errorf("image data changed")
+			}
+		})
+	}
+}
+
+// scanStart смещение маркера SOS, с которого начинаются данные изображения; false, если
+// файл кончается раньше.
+func scanStart(data []byte) (int, bool) {
+	for p := 2; p+4 <= len(data); {
+		if data[p+1] == markerSOS {
+			return p, true
+		}
+		p += 2 + int(binary.BigEndian.Uint16(data[p+2:]))
+	}
+	return 0, false
+}
+
+// jpegWithExif кодирует небольшое изображение в JPEG и добавляет к нему сегмент EXIF с tiff.
+func jpegWithExif(t testing.TB, tiff []byte) []byte {
+	t.Helper()
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append(append([]byte{}, exifHeader...), tiff...)
+	out := []byte{0xFF, markerSOI, 0xFF, markerAPP1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	out = append(out, payload...)
+	return append(out, img.Bytes()[2:]...)
+}
+
+func decodeJPEG(data []byte) (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(data))
+}
+
+func TestStripHEIC(t *testing.T) {
+	data := heicWithExif(sampleTIFF(t, "iphone-4s.jpg"), 2)
+	size := len(data)
+	if err := StripHEIC(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != size {
+		t.Errorf("size changed: %d -> %d", size, len(data))
+	}
+	if _, err := readBytes(data, "image/heic"); err == nil {
+		t.Error("exif still readable")
+	}
+	if bytes.Contains(data, []byte("iPhone")) {
+		t.Error("device name left in stripped file")
+	}
+}
+
+func FuzzRead(f *testing.F) {
+	for _, s := range phoneSamples {
+		jpg := readSample(f, s.file)
+		tiff := sampleTIFF(f, s.file)
+		f.Add(jpg[:min(len(jpg), 4096)])
+		f.Add(heicWithExif(tiff, 2))
+		f.Add(heicWithExif(tiff, 3))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, ct := range []string{"image/jpeg", "image/heic"} {
+			_, _ = readBytes(data, ct)
+		}
+		_, _ = parseTIFF(data)
+		_ = StripJPEG(io.Discard, bytes.NewReader(data))
+		_ = StripHEIC(append([]byte(nil), data...))
+	})
+}
+
+// tiffEntry запись IFD для buildTIFF; значение nil у тегов-ссылок на вложенные IFD и
+// у значений, которые должны указывать за конец данных.
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+}
+
+// buildTIFF собирает TIFF из IFD0 и одного вложенного IFD (Exif или GPS — по ссылке в IFD0).
+func buildTIFF(order interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}, ifd0, sub []tiffEntry) []byte {
+	ifdSize := func(n int) uint32 { return uint32(2 + 12*n + 4) }
+	subOffset := 8 + ifdSize(len(ifd0))
+	dataOffset := subOffset + ifdSize(len(sub))
+
+	out := []byte("II\x2A\x00")
+	if order.String() == binary.BigEndian.String() {
+		out = []byte("MM\x00\x2A")
+	}
+	out = order.AppendUint32(out, 8)
+	var blob []byte
+	for _, ifd := range [][]tiffEntry{ifd0, sub} {
+		out = order.AppendUint16(out, uint16(len(ifd)))
+		for _, e := range ifd {
+			out = order.AppendUint16(out, e.tag)
+			out = order.AppendUint16(out, e.typ)
+			out = order.AppendUint32(out, e.count)
+			switch {
+			case e.value == nil && (e.tag == tagExifIFD || e.tag == tagGPSIFD):
+				out = order.AppendUint32(out, subOffset)
+			case e.value == nil:
+				out = order.AppendUint32(out, dataOffset)
+			case len(e.value) <= 4:
+				out = append(out, e.value...)
+				out = append(out, make([]byte, 4-len(e.value))...)
+			default:
+				out = order.AppendUint32(out, dataOffset+uint32(len(blob)))
+				blob = append(blob, e.value...)
+			}
+		}
+		out = order.AppendUint32(out, 0)
+	}
+	return append(out, blob...)
+}
+
+func box8(typ string, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+// heicWithExif собирает минимальный HEIC (ftyp, meta с iinf и iloc, mdat) с элементом
+// Exif, в котором лежит tiff. infeVersion — 2 (item_ID u16) или 3 (item_ID u32).
+func heicWithExif(tiff []byte, infeVersion byte) []byte {
+	be := binary.BigEndian
+	ftyp := box8("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+	infeBody := []byte{infeVersion, 0, 0, 0}
+	if infeVersion == 3 {
+		infeBody = be.AppendUint32(infeBody, 1)
+	} else {
+		infeBody = be.AppendUint16(infeBody, 1)
+	}
+	infeBody = append(infeBody, 0, 0) // protection_index
+	infeBody = append(infeBody, "Exif"...)
+	iinf := box8("iinf", append([]byte{0, 0, 0, 0, 0, 1}, box8("infe", infeBody)...))
+
+	// как у камер: 4 байта смещения до TIFF, затем "Exif\0\0" и сам TIFF
+	payload := append(be.AppendUint32(nil, uint32(len(exifHeader))), exifHeader...)
+	payload = append(payload, tiff...)
+
+	iloc := func(offset uint32) []byte {
+		b := []byte{0, 0, 0, 0, 0x44, 0x00} // версия 0, offset_size = length_size = 4
+		b = be.AppendUint16(b, 1)           // item_count
+		b = be.AppendUint16(b, 1)           // item_ID
+		b = be.AppendUint16(b, 0)           // data_reference_index
+		b = be.AppendUint16(b, 1)           // extent_count
+		b = be.AppendUint32(b, offset)
+		b = be.AppendUint32(b, uint32(len(payload)))
+		return box8("iloc", b)
+	}
+	meta := func(offset uint32) []byte {
+		return box8("meta", append(append([]byte{0, 0, 0, 0}, iinf...), iloc(offset)...))
+	}
+	offset := uint32(len(ftyp) + len(meta(0)) + 8)
+	return append(append(ftyp, meta(offset)...), box8("mdat", payload)...)
+}
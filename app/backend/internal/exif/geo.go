@@ -0,0 +1,21 @@
+package exif
+
+import "math"
+
+// Options настройки обработки метаданных фото вложений дефектов.
+type Options struct {
+	// отдавать фото без EXIF и XMP (координаты, устройство, время съёмки)
+	Strip bool
+	// дальше этого расстояния от здания (м) фото помечается как снятое не на объекте
+	MaxDistance float64
+}
+
+const earthRadius = 6371000 // м
+
+// Distance расстояние между точками по большому кругу, м.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rlat1, rlat2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dLat, dLon := rlat2-rlat1, (lon2-lon1)*math.Pi/180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
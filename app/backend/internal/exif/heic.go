@@ -0,0 +1,218 @@
+package exif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// HEIC — контейнер ISO BMFF: EXIF лежит отдельным элементом типа "Exif", его
+// расположение в файле описано боксами iinf и iloc внутри meta.
+
+type box struct {
+	typ         string
+	start, size int64 // весь бокс
+	bodyStart   int64 // после заголовка
+}
+
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	for p := start; p+8 <= end; {
+		var h [16]byte
+		if _, err := r.ReadAt(h[:8], p); err != nil {
+			return nil, err
+		}
+		size, hdr := int64(binary.BigEndian.Uint32(h[:4])), int64(8)
+		switch size {
+		case 1:
+			if _, err := r.ReadAt(h[8:16], p+8); err != nil {
+				return nil, err
+			}
+			size, hdr = int64(binary.BigEndian.Uint64(h[8:16])), 16
+		case 0:
+			size = end - p
+		}
+		if size < hdr || size > end-p {
+			return nil, fmt.Errorf("exif: invalid box size")
+		}
+		boxes = append(boxes, box{typ: string(h[4:8]), start: p, size: size, bodyStart: p + hdr})
+		p += size
+	}
+	return boxes, nil
+}
+
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// heicExifRange возвращает смещение и длину данных элемента "Exif" в файле.
+func heicExifRange(r io.ReaderAt, size int64) (int64, int64, error) {
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	meta, ok := findBox(top, "meta")
+	if !ok {
+		return 0, 0, ErrNoExif
+	}
+	// meta — full box: версия и флаги перед дочерними боксами
+	children, err := readBoxes(r, meta.bodyStart+4, meta.start+meta.size)
+	if err != nil {
+		return 0, 0, err
+	}
+	iinf, ok1 := findBox(children, "iinf")
+	iloc, ok2 := findBox(children, "iloc")
+	if !ok1 || !ok2 {
+		return 0, 0, ErrNoExif
+	}
+
+	itemID, err := exifItemID(r, iinf)
+	if err != nil {
+		return 0, 0, err
+	}
+	return itemExtent(r, iloc, itemID)
+}
+
+func readBody(r io.ReaderAt, b box) ([]byte, error) {
+	n := b.start + b.size - b.bodyStart
+	if n > 1<<20 {
+		return nil, fmt.Errorf("exif: %s box is too large", b.typ)
+	}
+	buf := make([]byte, n)
+	_, err := r.ReadAt(buf, b.bodyStart)
+	return buf, err
+}
+
+// exifItemID ищет в iinf элемент с типом "Exif".
+func exifItemID(r io.ReaderAt, iinf box) (uint32, error) {
+	body, err := readBody(r, iinf)
+	if err != nil || len(body) < 6 {
+		return 0, ErrNoExif
+	}
+	p := int64(6) // версия, флаги, entry_count (u16)
+	if body[0] != 0 {
+		p = 8 // entry_count (u32)
+	}
+	entries, err := readBoxes(r, iinf.bodyStart+p, iinf.start+iinf.size)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if e.typ != "infe" {
+			continue
+		}
+		b, err := readBody(r, e)
+		if err != nil || len(b) < 4 {
+			continue
+		}
+		// infe версии 2: item_ID u16, protection_index u16, item_type; версии 3: item_ID u32
+		switch b[0] {
+		case 2:
+			if len(b) >= 12 && string(b[8:12]) == "Exif" {
+				return uint32(binary.BigEndian.Uint16(b[4:6])), nil
+			}
+		case 3:
+			if len(b) >= 14 && string(b[10:14]) == "Exif" {
+				return binary.BigEndian.Uint32(b[4:8]), nil
+			}
+		}
+	}
+	return 0, ErrNoExif
+}
+
+// itemExtent читает из iloc расположение элемента (только construction_method 0 — смещение
+// в файле — и один экстент, как пишут камеры телефонов).
+func itemExtent(r io.ReaderAt, iloc box, itemID uint32) (int64, int64, error) {
+	b, err := readBody(r, iloc)
+	if err != nil || len(b) < 8 {
+		return 0, 0, ErrNoExif
+	}
+	version := b[0]
+	offsetSize, lengthSize := int(b[4]>>4), int(b[4]&0x0F)
+	baseOffsetSize, indexSize := int(b[5]>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(b[5] & 0x0F)
+	}
+
+	p := 6
+	read := func(n int) (uint64, bool) {
+		if p+n > len(b) {
+			return 0, false
+		}
+		var v uint64
+		for i := 0; i < n; i++ {
+			v = v<<8 | uint64(b[p+i])
+		}
+		p += n
+		return v, true
+	}
+
+	countSize, idSize := 2, 2
+	if version == 2 {
+		countSize, idSize = 4, 4
+	}
+	count, ok := read(countSize)
+	if !ok {
+		return 0, 0, ErrNoExif
+	}
+	for i := uint64(0); i < count; i++ {
+		id, _ := read(idSize)
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			m, _ := read(2)
+			method = m & 0x0F
+		}
+		read(2) // data_reference_index
+		base, _ := read(baseOffsetSize)
+		extents, ok := read(2)
+		if !ok {
+			return 0, 0, ErrNoExif
+		}
+		var off, length uint64
+		for j := uint64(0); j < extents; j++ {
+			read(indexSize)
+			o, _ := read(offsetSize)
+			l, ok := read(lengthSize)
+			if !ok {
+				return 0, 0, ErrNoExif
+			}
+			if j == 0 {
+				off, length = o, l
+			}
+		}
+		if uint32(id) == itemID {
+			if method != 0 || extents != 1 {
+				return 0, 0, fmt.Errorf("exif: unsupported heic item location")
+			}
+			// смещение и длина из файла не должны переполнять int64
+			if base > math.MaxInt64-off || length > math.MaxInt64 {
+				return 0, 0, fmt.Errorf("exif: invalid heic item location")
+			}
+			return int64(base + off), int64(length), nil
+		}
+	}
+	return 0, 0, ErrNoExif
+}
+
+// heicTIFF читает данные элемента Exif: 4 байта смещения до заголовка TIFF (обычно
+// пропускают "Exif\0\0"), затем сам TIFF.
+func heicTIFF(r io.ReaderAt, off, n int64) ([]byte, error) {
+	if n < 8 || n > 1<<20 {
+		return nil, ErrNoExif
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	skip := int64(binary.BigEndian.Uint32(buf[:4])) + 4
+	if skip >= n {
+		return nil, ErrNoExif
+	}
+	return buf[skip:], nil
+}
@@ -0,0 +1,101 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// маркеры JPEG
+const (
+	markerSOI  = 0xD8
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// jpegExif возвращает TIFF-данные из сегмента APP1 "Exif".
+func jpegExif(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	var found []byte
+	err := walkJPEG(br, func(marker byte, payload []byte) (bool, error) {
+		if marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			found = payload[len(exifHeader):]
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNoExif
+	}
+	return found, nil
+}
+
+// walkJPEG читает сегменты заголовка JPEG до начала данных скана (SOS) и вызывает fn
+// для каждого; fn возвращает false, чтобы остановиться.
+func walkJPEG(br *bufio.Reader, fn func(marker byte, payload []byte) (bool, error)) error {
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != markerSOI {
+		return fmt.Errorf("exif: not a jpeg")
+	}
+	for {
+		marker, payload, err := readSegment(br)
+		if err != nil {
+			return err
+		}
+		if marker == markerSOS {
+			return nil
+		}
+		more, err := fn(marker, payload)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// readSegment читает маркер и тело сегмента (без длины). У SOS тело не читается.
+func readSegment(br *bufio.Reader) (byte, []byte, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	if b != 0xFF {
+		return 0, nil, fmt.Errorf("exif: invalid jpeg marker")
+	}
+	// перед маркером допускаются заполняющие 0xFF
+	marker := byte(0xFF)
+	for marker == 0xFF {
+		if marker, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+	}
+	if marker == markerSOS {
+		return marker, nil, nil
+	}
+	// маркеры без длины: TEM, RSTn
+	if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+		return marker, nil, nil
+	}
+	var l [2]byte
+	if _, err := io.ReadFull(br, l[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(l[:]))
+	if n < 2 {
+		return 0, nil, fmt.Errorf("exif: invalid jpeg segment length")
+	}
+	payload := make([]byte, n-2)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return 0, nil, err
+	}
+	return marker, payload, nil
+}
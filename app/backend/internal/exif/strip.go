@@ -0,0 +1,97 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Удаление метаданных из отдаваемой копии фото. Сохранённый оригинал не меняется.
+
+// StripJPEG копирует JPEG из src в dst без сегментов EXIF и XMP (там координаты,
+// устройство и время съёмки). Ориентация сохраняется: без неё браузер покажет фото
+// с телефона повёрнутым, поэтому вместо EXIF пишется минимальный сегмент только с ней.
+func StripJPEG(dst io.Writer, src io.Reader) error {
+	br := bufio.NewReader(src)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil {
+		return err
+	}
+	if _, err := dst.Write(soi[:]); err != nil {
+		return err
+	}
+	// не JPEG (например, старое вложение с неверным расширением) — отдаём как есть
+	if soi[0] != 0xFF || soi[1] != markerSOI {
+		_, err := io.Copy(dst, br)
+		return err
+	}
+
+	for {
+		marker, payload, err := readSegment(br)
+		if err != nil {
+			return err
+		}
+		if marker == markerSOS {
+			// дальше сжатые данные изображения — копируем как есть
+			if _, err := dst.Write([]byte{0xFF, markerSOS}); err != nil {
+				return err
+			}
+			_, err := io.Copy(dst, br)
+			return err
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			if m, err := parseTIFF(payload[len(exifHeader):]); err == nil && m.Orientation > 1 {
+				if _, err := dst.Write(orientationSegment(m.Orientation)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(payload, xmpHeader) {
+			continue
+		}
+
+		seg := []byte{0xFF, marker}
+		if payload != nil {
+			seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+			seg = append(seg, payload...)
+		}
+		if _, err := dst.Write(seg); err != nil {
+			return err
+		}
+	}
+}
+
+// orientationSegment сегмент APP1 с TIFF из одного тега Orientation.
+func orientationSegment(orientation int) []byte {
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08") // big-endian, IFD0 сразу после заголовка
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, tagOrientation)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // добивка значения и "следующего IFD нет"
+
+	payload := append(append([]byte{}, exifHeader...), tiff...)
+	seg := []byte{0xFF, markerAPP1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// StripHEIC затирает нулями элемент Exif в HEIC. Размер и структура файла не меняются;
+// ориентация в HEIC хранится в боксах irot/imir и не теряется.
+func StripHEIC(data []byte) error {
+	off, n, err := heicExifRange(bytes.NewReader(data), int64(len(data)))
+	if err == ErrNoExif {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if off < 0 || n < 0 || n > int64(len(data))-off {
+		return ErrNoExif
+	}
+	clear(data[off : off+n])
+	return nil
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"github.com/Quasar777/buildefect/app/backend/internal/common"
+)
+
+var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
+
+// Условие "просрочен": дефект не завершён, дедлайн задан (не нулевое время) и уже прошёл.
+// Аргументы: завершённые статусы схемы (workflow.Final), нулевое время, текущее время.
+const overdueCondition = "defects.status NOT IN ? AND defects.deadline > ? AND defects.deadline < ?"
+
+type AnalyticsHandler struct {
+	db       *gorm.DB
+	workflow *workflow.Workflow
+}
+
+func NewAnalyticsHandler(db *gorm.DB, wf *workflow.Workflow) *AnalyticsHandler {
+	return &AnalyticsHandler{db: db, workflow: wf}
+}
+
+// AnalyticsSummaryResponse общая сводка по дефектам.
+// swagger:model AnalyticsSummaryResponse
+type AnalyticsSummaryResponse struct {
+	// example: 240
+	Total         int64    `json:"total"`
+	// example: 95
+	Open          int64    `json:"open"`
+	// example: 12
+	Overdue       int64    `json:"overdue"`
+	// в статусе closed схемы workflow (работа выполнена)
+	// example: 130
+	Closed        int64    `json:"closed"`
+	// в остальных завершённых статусах схемы (final), например cancelled
+	// example: 15
+	Cancelled     int64    `json:"cancelled"`
+	// среднее время от создания до закрытия в часах, null если закрытых нет
+	// example: 73.5
+	AvgCloseHours *float64 `json:"avg_close_hours"`
+}
+
+// CountItem количество дефектов для значения группировки.
+// swagger:model CountItem
+type CountItem struct {
+	// example: in_progress
+	Key   string `json:"key"`
+	// example: 17
+	Count int64  `json:"count"`
+}
+
+// BuildingStat статистика дефектов по зданию.
+// swagger:model BuildingStat
+type BuildingStat struct {
+	// example: 1
+	BuildingID uint   `json:"building_id"`
+	// example: ЖК Солнечный
+	Name       string `json:"name"`
+	// example: 40
+	Total      int64  `json:"total"`
+	// example: 12
+	Open       int64  `json:"open"`
+	// example: 3
+	Overdue    int64  `json:"overdue"`
+}
+
+// ResponsibleStat открытые дефекты ответственного.
+// swagger:model ResponsibleStat
+type ResponsibleStat struct {
+	Responsible SimpleUser `json:"responsible"`
+	// example: 8
+	Open        int64      `json:"open"`
+	// example: 2
+	Overdue     int64      `json:"overdue"`
+}
+
+// Percentiles распределение длительности (в часах) по выборке дефектов.
+// swagger:model Percentiles
+type Percentiles struct {
+	// размер выборки
+	// example: 42
+	Count int64    `json:"count"`
+	// example: 4.5
+	P50   *float64 `json:"p50"`
+	// example: 26
+	P90   *float64 `json:"p90"`
+	// example: 48.2
+	P95   *float64 `json:"p95"`
+}
+
+// SLAMetrics перцентили сроков работы по группе дефектов.
+// swagger:model SLAMetrics
+type SLAMetrics struct {
+	// от создания до первого перехода из начального статуса схемы
+	FirstResponse Percentiles            `json:"first_response_hours"`
+	// от создания до закрытия (только закрытые дефекты)
+	Close         Percentiles            `json:"close_hours"`
+	// суммарное время в каждом статусе
+	TimeInStatus  map[string]Percentiles `json:"time_in_status_hours"`
+}
+
+// BuildingSLA сроки работы по дефектам здания.
+// swagger:model BuildingSLA
+type BuildingSLA struct {
+	// example: 1
+	BuildingID uint   `json:"building_id"`
+	// example: ЖК Солнечный
+	Name       string `json:"name"`
+	SLAMetrics
+}
+
+// ResponsibleSLA сроки работы по дефектам ответственного.
+// swagger:model ResponsibleSLA
+type ResponsibleSLA struct {
+	Responsible SimpleUser `json:"responsible"`
+	SLAMetrics
+}
+
+// Выражение перцентилей (PostgreSQL) по длительности %[1]s в часах
+const percentilesSelect = "COUNT(*) AS count, " +
+	"percentile_cont(0.5) WITHIN GROUP (ORDER BY %[1]s) AS p50, " +
+	"percentile_cont(0.9) WITHIN GROUP (ORDER BY %[1]s) AS p90, " +
+	"percentile_cont(0.95) WITHIN GROUP (ORDER BY %[1]s) AS p95"
+
+// baseQuery строит запрос по дефектам с фильтрами GetDefects; неизвестные параметры — ошибка,
+// чтобы опечатка в фильтре не давала молча посчитанную по всем дефектам сводку.
+func (h *AnalyticsHandler) baseQuery(c *fiber.Ctx) (*gorm.DB, error) {
+	if err := checkQueryParams(c, defectFilterParams...); err != nil {
+		return nil, err
+	}
+	return applyDefectFilters(c, h.db.Model(&models.Defect{}), h.workflow)
+}
+
+// parseDateParam разбирает дату в формате "2006-01-02" или "2006-01-02 15:04:05".
+// dateOnly = true, если время не указано.
+func parseDateParam(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.DateTime, v)
+	return t, false, err
+}
+
+// analyticsError отдаёт ошибку построения запроса: ошибки фильтров — 400.
+func analyticsError(c *fiber.Ctx, err error) error {
+	if fe, ok := err.(*fiber.Error); ok {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
+
+// GetSummary returns overall defect counters.
+// @Summary     Defect summary
+// @Description Total, open, overdue, closed and cancelled defect counts and average time to close (hours). Open and overdue exclude the workflow's final statuses; closed counts the workflow's closed status, cancelled the other final statuses. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {object}  AnalyticsSummaryResponse
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/summary [get]
+func (h *AnalyticsHandler) GetSummary(c *fiber.Ctx) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	final, closed := h.workflow.Final(), h.workflow.Closed()
+	var counts struct {
+		Total     int64
+		Open      int64
+		Overdue   int64
+		Closed    int64
+		Cancelled int64
+	}
+	if err := q.Session(&gorm.Session{}).Select(
+		"COUNT(*) AS total, "+
+			"COUNT(CASE WHEN defects.status NOT IN ? THEN 1 END) AS open, "+
+			"COUNT(CASE WHEN "+overdueCondition+" THEN 1 END) AS overdue, "+
+			"COUNT(CASE WHEN defects.status = ? THEN 1 END) AS closed, "+
+			"COUNT(CASE WHEN defects.status IN ? AND defects.status <> ? THEN 1 END) AS cancelled",
+		final, final, time.Time{}, time.Now(), closed, final, closed,
+	).Scan(&counts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	avg, err := h.avgCloseHours(q)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(AnalyticsSummaryResponse{
+		Total:         counts.Total,
+		Open:          counts.Open,
+		Overdue:       counts.Overdue,
+		Closed:        counts.Closed,
+		Cancelled:     counts.Cancelled,
+		AvgCloseHours: avg,
+	})
+}
+
+// avgCloseHours считает среднее время закрытия по закрытым дефектам из q.
+// Момент закрытия берётся из истории (последний переход в статус closed схемы),
+// для дефектов, закрытых до появления истории, — updated_at.
+func (h *AnalyticsHandler) avgCloseHours(q *gorm.DB) (*float64, error) {
+	closedAt := h.db.Model(&models.DefectHistory{}).
+		Select("defect_id, MAX(changed_at) AS closed_at").
+		Where("field = ? AND new_value = ?", "status", h.workflow.Closed()).
+		Group("defect_id")
+
+	var res struct {
+		AvgHours *float64
+	}
+	err := q.Session(&gorm.Session{}).
+		Joins("LEFT JOIN (?) AS closed_history ON closed_history.defect_id = defects.id", closedAt).
+		Where("defects.status = ?", h.workflow.Closed()).
+		Select("AVG(EXTRACT(EPOCH FROM (COALESCE(closed_history.closed_at, defects.updated_at) - defects.created_at)) / 3600) AS avg_hours").
+		Scan(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res.AvgHours, nil
+}
+
+// GetByStatus returns defect counts grouped by status.
+// @Summary     Defects by status
+// @Description Defect counts grouped by status. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   CountItem
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/by-status [get]
+func (h *AnalyticsHandler) GetByStatus(c *fiber.Ctx) error {
+	return h.countBy(c, "defects.status")
+}
+
+// GetByPriority returns defect counts grouped by priority.
+// @Summary     Defects by priority
+// @Description Defect counts grouped by priority. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   CountItem
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/by-priority [get]
+func (h *AnalyticsHandler) GetByPriority(c *fiber.Ctx) error {
+	return h.countBy(c, "defects.priority")
+}
+
+// countBy группирует дефекты по колонке column (только доверенные имена колонок!).
+func (h *AnalyticsHandler) countBy(c *fiber.Ctx, column string) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	items := make([]CountItem, 0)
+	if err := q.Select(column + " AS key, COUNT(*) AS count").
+		Group(column).
+		Order("count desc").
+		Scan(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(items)
+}
+
+// GetByBuilding returns defect counts per building.
+// @Summary     Defects by building
+// @Description Total, open and overdue defect counts per building. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   BuildingStat
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/by-building [get]
+func (h *AnalyticsHandler) GetByBuilding(c *fiber.Ctx) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	stats := make([]BuildingStat, 0)
+	if err := q.Joins("JOIN buildings ON buildings.id = defects.building_id").
+		Select(
+			"defects.building_id AS building_id, buildings.name AS name, COUNT(*) AS total, "+
+				"COUNT(CASE WHEN defects.status NOT IN ? THEN 1 END) AS open, "+
+				"COUNT(CASE WHEN "+overdueCondition+" THEN 1 END) AS overdue",
+			h.workflow.Final(), h.workflow.Final(), time.Time{}, time.Now(),
+		).
+		Group("defects.building_id, buildings.name").
+		Order("total desc").
+		Scan(&stats).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+// GetByResponsible returns open defects per responsible engineer.
+// @Summary     Open defects by responsible
+// @Description Open and overdue defect counts per responsible user (unassigned defects are not included). Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   ResponsibleStat
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/by-responsible [get]
+func (h *AnalyticsHandler) GetByResponsible(c *fiber.Ctx) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	var rows []struct {
+		models.User
+		Open    int64
+		Overdue int64
+	}
+	if err := q.Joins("JOIN users ON users.id = defects.responsible_person_id").
+		Where("defects.status NOT IN ?", h.workflow.Final()).
+		Select(
+			"users.id, users.login, users.name, users.last_name, users.role, "+
+				"COUNT(*) AS open, COUNT(CASE WHEN "+overdueCondition+" THEN 1 END) AS overdue",
+			h.workflow.Final(), time.Time{}, time.Now(),
+		).
+		Group("users.id, users.login, users.name, users.last_name, users.role").
+		Order("open desc").
+		Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := make([]ResponsibleStat, 0, len(rows))
+	for _, r := range rows {
+		resp = append(resp, ResponsibleStat{
+			Responsible: toSimpleUser(r.User),
+			Open:        r.Open,
+			Overdue:     r.Overdue,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSLAByBuilding returns time-in-status, first response and close time percentiles per building.
+// @Summary     SLA percentiles by building
+// @Description p50/p90/p95 of time to first response, time to close and time spent in each status (hours), per building. Only defects with recorded status periods are counted. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   BuildingSLA
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/sla/by-building [get]
+func (h *AnalyticsHandler) GetSLAByBuilding(c *fiber.Ctx) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	groups, err := h.slaBy(q, "defects.building_id")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	// Unscoped: здание могло быть удалено, но его дефекты остались в выборке
+	var buildings []models.Building
+	if err := h.db.Unscoped().Where("id IN ?", sortedKeys(groups)).Find(&buildings).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	names := make(map[uint]string, len(buildings))
+	for _, b := range buildings {
+		names[b.ID] = b.Name
+	}
+
+	resp := make([]BuildingSLA, 0, len(groups))
+	for _, id := range sortedKeys(groups) {
+		resp = append(resp, BuildingSLA{BuildingID: id, Name: names[id], SLAMetrics: *groups[id]})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetSLAByResponsible returns time-in-status, first response and close time percentiles per responsible user.
+// @Summary     SLA percentiles by responsible
+// @Description p50/p90/p95 of time to first response, time to close and time spent in each status (hours), per responsible user. Unassigned defects and defects without recorded status periods are not counted. Accepts the same filters as the defect list.
+// @Tags        analytics
+// @Accept      json
+// @Produce     json
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Success     200  {array}   ResponsibleSLA
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/analytics/sla/by-responsible [get]
+func (h *AnalyticsHandler) GetSLAByResponsible(c *fiber.Ctx) error {
+	q, err := h.baseQuery(c)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	groups, err := h.slaBy(q.Where("defects.responsible_person_id <> 0"), "defects.responsible_person_id")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var users []models.User
+	if err := h.db.Unscoped().Where("id IN ?", sortedKeys(groups)).Find(&users).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	resp := make([]ResponsibleSLA, 0, len(groups))
+	for _, id := range sortedKeys(groups) {
+		u := byID[id]
+		u.ID = id
+		resp = append(resp, ResponsibleSLA{Responsible: toSimpleUser(u), SLAMetrics: *groups[id]})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// slaBy считает перцентили сроков по дефектам из q, сгруппированным по колонке groupColumn
+// (только доверенные имена колонок!). Длительности берутся из интервалов статусов.
+func (h *AnalyticsHandler) slaBy(q *gorm.DB, groupColumn string) (map[uint]*SLAMetrics, error) {
+	groups := make(map[uint]*SLAMetrics)
+	group := func(id uint) *SLAMetrics {
+		if groups[id] == nil {
+			groups[id] = &SLAMetrics{TimeInStatus: make(map[string]Percentiles)}
+		}
+		return groups[id]
+	}
+
+	type row struct {
+		GroupID uint
+		Status  string
+		Percentiles
+	}
+	now := time.Now()
+
+	// время до первой реакции: первый вход в любой статус, кроме начального статуса схемы
+	firstResponse := h.db.Model(&models.DefectStatusPeriod{}).
+		Select("defect_id, MIN(entered_at) AS responded_at").
+		Where("status <> ?", h.workflow.Initial()).
+		Group("defect_id")
+	var rows []row
+	if err := q.Session(&gorm.Session{}).
+		Joins("JOIN (?) AS first_response ON first_response.defect_id = defects.id", firstResponse).
+		Select(groupColumn + " AS group_id, " +
+			fmt.Sprintf(percentilesSelect, "EXTRACT(EPOCH FROM (first_response.responded_at - defects.created_at)) / 3600")).
+		Group(groupColumn).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		group(r.GroupID).FirstResponse = r.Percentiles
+	}
+
+	// время до закрытия: последний вход в статус closed схемы у закрытых дефектов
+	closedAt := h.db.Model(&models.DefectStatusPeriod{}).
+		Select("defect_id, MAX(entered_at) AS closed_at").
+		Where("status = ?", h.workflow.Closed()).
+		Group("defect_id")
+	rows = nil
+	if err := q.Session(&gorm.Session{}).
+		Joins("JOIN (?) AS closed_period ON closed_period.defect_id = defects.id", closedAt).
+		Where("defects.status = ?", h.workflow.Closed()).
+		Select(groupColumn + " AS group_id, " +
+			fmt.Sprintf(percentilesSelect, "EXTRACT(EPOCH FROM (closed_period.closed_at - defects.created_at)) / 3600")).
+		Group(groupColumn).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		group(r.GroupID).Close = r.Percentiles
+	}
+
+	// время в статусах: сумма интервалов по каждому (дефект, статус), текущий интервал — до now
+	perStatus := h.db.Model(&models.DefectStatusPeriod{}).
+		Select("defect_id, status, SUM(EXTRACT(EPOCH FROM (COALESCE(left_at, ?) - entered_at))) / 3600 AS hours", now).
+		Group("defect_id, status")
+	rows = nil
+	if err := q.Session(&gorm.Session{}).
+		Joins("JOIN (?) AS per_status ON per_status.defect_id = defects.id", perStatus).
+		Select(groupColumn + " AS group_id, per_status.status AS status, " +
+			fmt.Sprintf(percentilesSelect, "per_status.hours")).
+		Group(groupColumn + ", per_status.status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		group(r.GroupID).TimeInStatus[r.Status] = r.Percentiles
+	}
+
+	return groups, nil
+}
+
+func sortedKeys(m map[uint]*SLAMetrics) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/common"
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
+
+// AttachmentExportHandler выгружает вложения дефектов одним ZIP-архивом.
+type AttachmentExportHandler struct {
+	db     *gorm.DB
+	store  storage.Storage
+	photos exif.Options
+}
+
+func NewAttachmentExportHandler(db *gorm.DB, store storage.Storage, photos exif.Options) *AttachmentExportHandler {
+	return &AttachmentExportHandler{db: db, store: store, photos: photos}
+}
+
+// максимальная длина названия дефекта в имени папки, символов
+const exportTitleLen = 60
+
+var exportNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "<", "_", ">", "_", "|", "_")
+
+// exportEntry файл архива и строка манифеста.
+type exportEntry struct {
+	DefectID     uint
+	DefectTitle  string
+	Source       string // defect или comment
+	CommentID    uint
+	AttachmentID uint
+	Path         string // путь внутри архива
+	Key          string
+	FileName     string
+	ContentType  string
+	Size         int64
+	Hash         string
+	TakenAt      *time.Time
+	Status       string // ok, missing (нет в хранилище) или error
+}
+
+// ExportDefectAttachments отдаёт ZIP со всеми вложениями дефекта.
+// @Summary     Export defect attachments as ZIP
+// @Description Stream a ZIP archive with all files of the defect: defect attachments in the folder "<defect id>_<title>/", comment attachments in its "comments/" subfolder, and manifest.csv at the root. The archive is streamed as it is built, without Content-Length. Files missing in storage are skipped and marked "missing" in the manifest. With EXIF_STRIP enabled, photos are exported without EXIF metadata.
+// @Tags        attachment-export
+// @Produce     application/zip
+// @Param       id  path  int  true  "Defect ID"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id}/attachments/export [get]
+func (h *AttachmentExportHandler) ExportDefectAttachments(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid defect id"})
+	}
+
+	var defect models.Defect
+	if err := h.db.First(&defect, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	entries, err := h.collectEntries([]models.Defect{defect})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get attachments"})
+	}
+
+	return h.sendZip(c, fmt.Sprintf("defect_%d_attachments.zip", defect.ID), entries)
+}
+
+// ExportBuildingAttachments отдаёт ZIP со вложениями всех дефектов здания.
+// @Summary     Export building attachments as ZIP
+// @Description Stream a ZIP archive with the files of all defects of the building (defects in the trash are not included). Each defect gets its own folder "<defect id>_<title>/" with comment attachments in its "comments/" subfolder; manifest.csv at the root lists every file. The archive is streamed as it is built, without Content-Length.
+// @Tags        attachment-export
+// @Produce     application/zip
+// @Param       id  path  int  true  "Building ID"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/buildings/{id}/attachments/export [get]
+func (h *AttachmentExportHandler) ExportBuildingAttachments(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid building id"})
+	}
+
+	var building models.Building
+	if err := h.db.First(&building, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "building not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var defects []models.Defect
+	if err := h.db.Where("building_id = ?", building.ID).Order("id").Find(&defects).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	entries, err := h.collectEntries(defects)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get attachments"})
+	}
+
+	return h.sendZip(c, fmt.Sprintf("building_%d_attachments.zip", building.ID), entries)
+}
+
+// collectEntries собирает файлы дефектов и их комментариев (без комментариев из корзины)
+// в порядке дефектов. Запросы к БД выполняются до начала отправки архива, чтобы
+// ошибку ещё можно было вернуть обычным ответом.
+func (h *AttachmentExportHandler) collectEntries(defects []models.Defect) ([]exportEntry, error) {
+	if len(defects) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, 0, len(defects))
+	for _, d := range defects {
+		ids = append(ids, d.ID)
+	}
+
+	var defectAttachments []models.DefectAttachment
+	if err := h.db.Where("defect_id IN ?", ids).Order("id").Find(&defectAttachments).Error; err != nil {
+		return nil, err
+	}
+	var commentAttachments []models.CommentAttachment
+	commentIDs := h.db.Model(&models.Comment{}).Select("id").Where("defect_id IN ?", ids)
+	if err := h.db.Preload("Comment").Where("comment_id IN (?)", commentIDs).Order("id").Find(&commentAttachments).Error; err != nil {
+		return nil, err
+	}
+
+	byDefect := map[uint][]exportEntry{}
+	for _, a := range defectAttachments {
+		byDefect[a.DefectID] = append(byDefect[a.DefectID], exportEntry{
+			Source:       "defect",
+			AttachmentID: a.ID,
+			Key:          a.URL,
+			FileName:     attachmentFilename(a.FileName, a.URL),
+			ContentType:  a.ContentType,
+			Size:         a.Size,
+			Hash:         a.Hash,
+			TakenAt:      a.TakenAt,
+		})
+	}
+	for _, a := range commentAttachments {
+		byDefect[a.Comment.DefectID] = append(byDefect[a.Comment.DefectID], exportEntry{
+			Source:       "comment",
+			CommentID:    a.CommentID,
+			AttachmentID: a.ID,
+			Key:          a.URL,
+			FileName:     attachmentFilename(a.FileName, a.URL),
+			ContentType:  a.ContentType,
+			Size:         a.Size,
+			Hash:         a.Hash,
+		})
+	}
+
+	var entries []exportEntry
+	for _, d := range defects {
+		folder := exportFolder(d)
+		for _, e := range byDefect[d.ID] {
+			e.DefectID = d.ID
+			e.DefectTitle = d.Title
+			// ID вложения в начале имени: одинаковые имена файлов не конфликтуют
+			name := strconv.Itoa(int(e.AttachmentID)) + "_" + e.FileName
+			if e.Source == "comment" {
+				e.Path = folder + "/comments/" + name
+			} else {
+				e.Path = folder + "/" + name
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// exportFolder имя папки дефекта в архиве: "<id>_<название>".
+func exportFolder(d models.Defect) string {
+	title := strings.TrimSpace(d.Title)
+	if title == "" {
+		return strconv.Itoa(int(d.ID))
+	}
+	// "/" в названии — не вложенная папка; остальные символы запрещены в именах файлов Windows
+	title = upload.SanitizeFilename(exportNameReplacer.Replace(title))
+	if r := []rune(title); len(r) > exportTitleLen {
+		title = strings.TrimSpace(string(r[:exportTitleLen]))
+	}
+	return strconv.Itoa(int(d.ID)) + "_" + title
+}
+
+// sendZip отдаёт архив потоком: файлы читаются из хранилища и пишутся в ответ по одному,
+// архив целиком в памяти не собирается.
+func (h *AttachmentExportHandler) sendZip(c *fiber.Ctx, filename string, entries []exportEntry) error {
+	// контекст запроса сохраняется заранее: после возврата из обработчика c использовать нельзя
+	ctx := c.UserContext()
+	c.Attachment(filename)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.writeZip(ctx, w, entries); err != nil {
+			log.Warn().Err(err).Str("archive", filename).Msg("attachment export interrupted")
+		}
+	})
+	return nil
+}
+
+func (h *AttachmentExportHandler) writeZip(ctx context.Context, w *bufio.Writer, entries []exportEntry) error {
+	zw := zip.NewWriter(w)
+	for i := range entries {
+		e := &entries[i]
+		if err := h.writeEntry(ctx, zw, e); err != nil {
+			return err
+		}
+		// отправляем клиенту каждый файл сразу; ошибка — клиент отключился
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeManifest(zw, entries); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// writeEntry добавляет файл в архив. Отсутствующий или недоступный файл пропускается
+// с отметкой в манифесте; ошибка возвращается, только если архив дальше писать нельзя.
+func (h *AttachmentExportHandler) writeEntry(ctx context.Context, zw *zip.Writer, e *exportEntry) error {
+	obj, err := h.store.Get(ctx, e.Key)
+	if err != nil {
+		e.Status = "error"
+		if errors.Is(err, storage.ErrNotFound) {
+			e.Status = "missing"
+		}
+		log.Warn().Err(err).Str("key", e.Key).Msg("attachment export: failed to open file")
+		return nil
+	}
+	defer obj.Body.Close()
+
+	hdr := &zip.FileHeader{Name: e.Path, Method: zip.Deflate, Modified: obj.ModTime}
+	// фото и PDF уже сжаты, повторное сжатие только тратит процессор
+	if strings.HasPrefix(e.ContentType, "image/") || e.ContentType == "application/pdf" {
+		hdr.Method = zip.Store
+	}
+	fw, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	if h.photos.Strip {
+		err = copyWithoutExif(fw, obj.Body, obj.ContentType)
+	} else {
+		_, err = io.Copy(fw, obj.Body)
+	}
+	if err != nil {
+		// часть файла уже в архиве, продолжать нельзя
+		return fmt.Errorf("%s: %w", e.Key, err)
+	}
+	e.Status = "ok"
+	return nil
+}
+
+// writeManifest добавляет manifest.csv со списком файлов архива. BOM нужен, чтобы Excel
+// открывал кириллицу в UTF-8.
+func writeManifest(zw *zip.Writer, entries []exportEntry) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "manifest.csv", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, "\ufeff"); err != nil {
+		return err
+	}
+
+	// ошибки отдельных Write не проверяются: csv.Writer запоминает первую, её вернёт cw.Error() после Flush
+	cw := csv.NewWriter(fw)
+	cw.Write([]string{"defect_id", "defect_title", "source", "comment_id", "attachment_id", "path", "filename", "content_type", "size", "sha256", "taken_at", "status"})
+	for _, e := range entries {
+		commentID, takenAt := "", ""
+		if e.CommentID != 0 {
+			commentID = strconv.Itoa(int(e.CommentID))
+		}
+		if e.TakenAt != nil {
+			takenAt = e.TakenAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			strconv.Itoa(int(e.DefectID)),
+			csvText(e.DefectTitle),
+			e.Source,
+			commentID,
+			strconv.Itoa(int(e.AttachmentID)),
+			csvText(e.Path),
+			csvText(e.FileName),
+			e.ContentType,
+			strconv.FormatInt(e.Size, 10),
+			e.Hash,
+			takenAt,
+			e.Status,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText защищает текст пользователя в манифесте от выполнения как формулы в Excel и
+// LibreOffice: ячейка, начинающаяся с =, +, -, @, табуляции или возврата каретки, получает
+// префикс «'».
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
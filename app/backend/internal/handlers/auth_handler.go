@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"strconv"
-	"time"
+	"errors"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/models"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"github.com/Quasar777/buildefect/app/backend/internal/common"
@@ -15,16 +14,14 @@ import (
 var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
 
 type AuthHandler struct {
-	db        *gorm.DB
-	jwtSecret string
-	ttl       time.Duration
+	db     *gorm.DB
+	tokens *auth.Manager
 }
 
-func NewAuthHandler(db *gorm.DB, jwtSecret string, ttl time.Duration) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, tokens *auth.Manager) *AuthHandler {
 	return &AuthHandler{
 		db: db, 
-		jwtSecret: jwtSecret, 
-		ttl: ttl,
+		tokens: tokens,
 	}
 }
 
@@ -60,6 +57,17 @@ type TokenResponse struct {
     TokenType   string `json:"token_type"`
     // seconds until token expiration
     ExpiresIn   int64  `json:"expires_in"`
+    // one-time token to obtain a new pair via /api/auth/refresh
+    RefreshToken string `json:"refresh_token"`
+    // seconds until refresh token expiration
+    RefreshExpiresIn int64 `json:"refresh_expires_in"`
+}
+
+// RefreshRequest carries refresh token for refresh and logout.
+// swagger:model RefreshRequest
+type RefreshRequest struct {
+    // example: 3f9c0a7d1e2b4c5d6e7f8091a2b3c4d5.q1w2e3r4t5y6u7i8o9p0
+    RefreshToken string `json:"refresh_token"`
 }
 
 // Register registers a new user (no token returned).
@@ -83,7 +91,8 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "login and password required"})
 	}
 	var cnt int64
-	if err := h.db.Model(&models.User{}).Where("login = ?", req.Login).Count(&cnt).Error; err != nil {
+	// Unscoped: логин удалённого пользователя остаётся занятым (на случай восстановления)
+	if err := h.db.Unscoped().Model(&models.User{}).Where("login = ?", req.Login).Count(&cnt).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 	if cnt > 0 {
@@ -118,7 +127,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 // Login authenticates user and returns JWT token.
 // @Summary     Login and obtain JWT
-// @Description Validate credentials and return access token with expiry seconds
+// @Description Validate credentials, open a new session and return a short-lived access token and a refresh token with expiry seconds
 // @Tags        auth
 // @Accept      json
 // @Produce     json
@@ -145,27 +154,97 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
 	}
 
-	// creating jwt payload
-	now := time.Now()
-	exp := now.Add(h.ttl)
-	claims := jwt.MapClaims{
-		"sub":   strconv.FormatUint(uint64(user.ID), 10),
-		"login": user.Login,
-		"role":  user.Role,
-		"iat":   now.Unix(),
-		"exp":   exp.Unix(),
+	// новый сеанс: короткий access-токен и одноразовый refresh-токен
+	tokens, err := h.tokens.StartSession(user, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create session"})
 	}
 
-	// generating and signing token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(h.jwtSecret))
+	return c.Status(fiber.StatusOK).JSON(newTokenResponse(tokens))
+}
+
+// Refresh exchanges refresh token for a new token pair.
+// @Summary     Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. The presented refresh token becomes invalid; presenting it again revokes the whole session.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       payload  body      RefreshRequest  true  "Refresh token"
+// @Success     200      {object}  TokenResponse
+// @Failure     400      {object}  common.ErrorResponse  "invalid request body"
+// @Failure     401      {object}  common.ErrorResponse  "invalid, expired, reused or revoked refresh token"
+// @Failure     500      {object}  common.ErrorResponse
+// @Router      /api/auth/refresh [post]
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token required"})
+	}
+
+	tokens, err := h.tokens.Refresh(req.RefreshToken)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sign token"})
+		switch {
+		case errors.Is(err, auth.ErrInvalidRefreshToken):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid refresh token"})
+		case errors.Is(err, auth.ErrRefreshTokenExpired):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token expired"})
+		case errors.Is(err, auth.ErrRefreshTokenReused):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "refresh token reuse detected, session revoked"})
+		case errors.Is(err, auth.ErrSessionRevoked):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session revoked"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to refresh session"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(TokenResponse{
-		AccessToken: signed,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(h.ttl.Seconds()),
-	})
-}
\ No newline at end of file
+	return c.Status(fiber.StatusOK).JSON(newTokenResponse(tokens))
+}
+
+// Logout revokes the session of the refresh token.
+// @Summary     Logout
+// @Description Revoke the session the refresh token belongs to. Access tokens of the session stop working immediately. Repeated logout is not an error.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       payload  body      RefreshRequest  true  "Refresh token"
+// @Success     204      "session revoked"
+// @Failure     400      {object}  common.ErrorResponse  "invalid request body or malformed refresh token"
+// @Failure     500      {object}  common.ErrorResponse
+// @Router      /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token required"})
+	}
+
+	if err := h.tokens.Logout(req.RefreshToken); err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid refresh token"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to revoke session"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// JWKS returns public keys for access token verification.
+// @Summary     JSON Web Key Set
+// @Description Public parts of RS256/EdDSA keys that sign access tokens, identified by kid (RFC 7517). HMAC keys are never published. Other services verify tokens with these keys and must check iss and aud.
+// @Tags        auth
+// @Produce     json
+// @Success     200  {object}  auth.JWKS
+// @Router      /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *fiber.Ctx) error {
+	// ключи меняются редко, но после ротации клиенты должны увидеть новый kid быстро
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(h.tokens.JWKS())
+}
+
+func newTokenResponse(tokens auth.Tokens) TokenResponse {
+	return TokenResponse{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(tokens.AccessTTL.Seconds()),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresIn: int64(tokens.RefreshTTL.Seconds()),
+	}
+}
@@ -29,6 +29,11 @@ type CreateBuildingRequest struct {
     Address string `json:"address"`
     // example: построено
     Stage   string `json:"stage"`
+    // широта и долгота объекта, задаются вместе
+    // example: 59.9358
+    Latitude  *float64 `json:"latitude"`
+    // example: 30.3259
+    Longitude *float64 `json:"longitude"`
 }
 
 // BuildingResponse описывает структуру ответа для здания.
@@ -38,6 +43,8 @@ type BuildingResponse struct {
     Name    string `json:"name"`
     Address string `json:"address"`
     Stage   string `json:"stage"`
+    Latitude  *float64 `json:"latitude"`
+    Longitude *float64 `json:"longitude"`
 }
 
 // UpdateBuildingRequest используется для частичного обновления здания.
@@ -49,17 +56,37 @@ type UpdateBuildingRequest struct {
     Address string `json:"address"`
     // example: в_строительстве
     Stage   string `json:"stage"`
+    // example: 59.9358
+    Latitude  *float64 `json:"latitude"`
+    // example: 30.3259
+    Longitude *float64 `json:"longitude"`
 }
 
 func toBuildingResponse(b models.Building) BuildingResponse {
 	return BuildingResponse{
-		ID:      b.ID,
-		Name:    b.Name,
-		Address: b.Address,
-		Stage:   b.Stage,
+		ID:        b.ID,
+		Name:      b.Name,
+		Address:   b.Address,
+		Stage:     b.Stage,
+		Latitude:  b.Latitude,
+		Longitude: b.Longitude,
 	}
 }
 
+// validateCoordinates: широта и долгота задаются только вместе и в допустимых пределах.
+func validateCoordinates(lat, lon *float64) error {
+	if (lat == nil) != (lon == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if lat != nil && (*lat < -90 || *lat > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if lon != nil && (*lon < -180 || *lon > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
 // CreateBuilding creates a new building.
 // @Summary     Create building
 // @Description Create a new building record
@@ -81,11 +108,16 @@ func (h *BuildingHandler) CreateBuilding(c *fiber.Ctx) error {
 	if req.Name == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
 	}
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	building := models.Building{
-		Name:    req.Name,
-		Address: req.Address,
-		Stage:   req.Stage,
+		Name:      req.Name,
+		Address:   req.Address,
+		Stage:     req.Stage,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
 	}
 
 	if err := h.db.Create(&building).Error; err != nil {
@@ -97,25 +129,39 @@ func (h *BuildingHandler) CreateBuilding(c *fiber.Ctx) error {
 
 // GetBuildings returns list of buildings.
 // @Summary     List buildings
-// @Description Retrieve list of all buildings
+// @Description Retrieve buildings page by page (offset or keyset cursor on created_at, id)
 // @Tags        buildings
 // @Accept      json
 // @Produce     json
-// @Success     200  {array}  BuildingResponse
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object} ListResponse[BuildingResponse]
+// @Failure     400  {object} common.ErrorResponse "invalid query param"
 // @Failure     500  {object} common.ErrorResponse
 // @Router      /api/buildings [get]
 func (h *BuildingHandler) GetBuildings(c *fiber.Ctx) error {
-	var buildings []models.Building
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	if err := h.db.Find(&buildings).Error; err != nil {
+	var buildings []models.Building
+	q := keysetOrder(h.db.Model(&models.Building{}), "buildings", false, page)
+	total, hasMore, err := findPage(h.db.Model(&models.Building{}), q, page, &buildings)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "database error",
 		})
 	}
 
-	resp := make([]BuildingResponse, 0, len(buildings))
+	resp := ListResponse[BuildingResponse]{Items: make([]BuildingResponse, 0, len(buildings)), Total: total}
 	for _, b := range buildings {
-		resp = append(resp, toBuildingResponse(b))
+		resp.Items = append(resp.Items, toBuildingResponse(b))
+	}
+	if hasMore {
+		last := buildings[len(buildings)-1]
+		resp.NextCursor = nextCursor(true, last.CreatedAt, last.ID)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -155,7 +201,7 @@ func (h *BuildingHandler) GetBuilding(c *fiber.Ctx) error {
 
 // UpdateBuilding updates building fields partially.
 // @Summary     Update building
-// @Description Partially update building (name/address/stage)
+// @Description Partially update building (name/address/stage/coordinates). Latitude and longitude are updated together.
 // @Tags        buildings
 // @Accept      json
 // @Produce     json
@@ -191,9 +237,11 @@ func (h *BuildingHandler) UpdateBuilding(c *fiber.Ctx) error {
 	// TODO: Наверно можно убрать, если я вынес это вверху
 	// DTO для частичного обновления
 	type UpdateBuildingReq struct {
-		Name    string `json:"name"`
-		Address string `json:"address"`
-		Stage   string `json:"stage"`
+		Name      string   `json:"name"`
+		Address   string   `json:"address"`
+		Stage     string   `json:"stage"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
 	}
 
 	var req UpdateBuildingReq
@@ -210,6 +258,12 @@ func (h *BuildingHandler) UpdateBuilding(c *fiber.Ctx) error {
 	if req.Stage != "" {
 		building.Stage = req.Stage
 	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		building.Latitude, building.Longitude = req.Latitude, req.Longitude
+	}
 
 	if err := h.db.Save(&building).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save building"})
@@ -218,9 +272,9 @@ func (h *BuildingHandler) UpdateBuilding(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(toBuildingResponse(building))
 }
 
-// DeleteBuilding removes building by id.
+// DeleteBuilding moves building to trash.
 // @Summary     Delete building
-// @Description Delete building by id
+// @Description Soft-delete building by id. The building can be restored or purged from trash. A building with defects outside the trash cannot be deleted: delete its defects first.
 // @Tags        buildings
 // @Accept      json
 // @Produce     plain
@@ -228,6 +282,8 @@ func (h *BuildingHandler) UpdateBuilding(c *fiber.Ctx) error {
 // @Success     200  {string}  string "Successfully deleted building with id {id}"
 // @Failure     400  {object}  common.ErrorResponse
 // @Failure     404  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     409  {object}  common.ErrorResponse  "building still has defects"
 // @Failure     500  {object}  common.ErrorResponse
 // @Security    BearerAuth
 // @Router      /api/buildings/{id} [delete]
@@ -252,7 +308,21 @@ func (h *BuildingHandler) DeleteBuilding(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.db.Delete(&building).Error; err != nil {
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	// дефекты здания из корзины остались бы видны в списках, поиске и аналитике
+	var cnt int64
+	if err := h.db.Model(&models.Defect{}).Where("building_id = ?", building.ID).Count(&cnt).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if cnt > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "building still has defects, delete them first"})
+	}
+
+	if err := softDelete(h.db, &building, uid); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to delete building",
 		})
@@ -260,3 +330,135 @@ func (h *BuildingHandler) DeleteBuilding(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).SendString("Successfully deleted building with id " + strconv.Itoa(int(building.ID)))
 }
+
+// DeletedBuildingResponse описывает здание в корзине.
+// swagger:model DeletedBuildingResponse
+type DeletedBuildingResponse struct {
+	BuildingResponse
+	DeletionInfo
+}
+
+// GetDeletedBuildings returns buildings in trash.
+// @Summary     List deleted buildings
+// @Description Retrieve soft-deleted buildings page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)
+// @Tags        buildings
+// @Accept      json
+// @Produce     json
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object}  ListResponse[DeletedBuildingResponse]
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/buildings/trash [get]
+func (h *BuildingHandler) GetDeletedBuildings(c *fiber.Ctx) error {
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var buildings []models.Building
+	q := trashOrder(trashScope(h.db.Model(&models.Building{})), "buildings", page)
+	total, hasMore, err := findPage(trashScope(h.db.Model(&models.Building{})), q, page, &buildings)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[DeletedBuildingResponse]{Items: make([]DeletedBuildingResponse, 0, len(buildings)), Total: total}
+	for _, b := range buildings {
+		resp.Items = append(resp.Items, DeletedBuildingResponse{
+			BuildingResponse: toBuildingResponse(b),
+			DeletionInfo:     toDeletionInfo(b.DeletedAt, b.DeletedByPersonID),
+		})
+	}
+	if hasMore {
+		last := buildings[len(buildings)-1]
+		resp.NextCursor = nextCursor(true, last.DeletedAt.Time, last.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreBuilding restores building from trash.
+// @Summary     Restore building
+// @Description Restore a soft-deleted building
+// @Tags        buildings
+// @Accept      json
+// @Produce     json
+// @Param       id   path      int  true  "Building ID"
+// @Success     200  {object}  BuildingResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted building not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/buildings/{id}/restore [post]
+func (h *BuildingHandler) RestoreBuilding(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var building models.Building
+	if err := findDeleted(h.db, &building, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted building not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	if err := restoreDeleted(h.db, &building); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to restore building"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toBuildingResponse(building))
+}
+
+// PurgeBuilding permanently deletes a building from trash.
+// @Summary     Purge building
+// @Description Permanently delete a soft-deleted building. Not allowed while any defect (including deleted ones) references it.
+// @Tags        buildings
+// @Accept      json
+// @Produce     plain
+// @Param       id   path      int  true  "Building ID"
+// @Success     200  {string}  string  "Permanently deleted building with id {id}"
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted building not found"
+// @Failure     409  {object}  common.ErrorResponse  "building still has defects"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/buildings/{id}/purge [delete]
+func (h *BuildingHandler) PurgeBuilding(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var building models.Building
+	if err := findDeleted(h.db, &building, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted building not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var cnt int64
+	if err := h.db.Unscoped().Model(&models.Defect{}).Where("building_id = ?", building.ID).Count(&cnt).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if cnt > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "building still has defects, purge them first"})
+	}
+
+	if err := h.db.Unscoped().Delete(&building).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to purge building"})
+	}
+
+	return c.Status(fiber.StatusOK).SendString("Permanently deleted building with id " + strconv.Itoa(int(building.ID)))
+}
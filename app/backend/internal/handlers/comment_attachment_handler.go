@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"github.com/Quasar777/buildefect/app/backend/internal/common"
+)
+
+var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
+
+type CommentAttachmentHandler struct {
+	db     *gorm.DB
+	store  storage.Storage
+	policy upload.Policy
+	signer *signedurl.Signer
+	photos exif.Options
+}
+
+// CommentAttachmentResponse описывает файл вложения комментария.
+// swagger:model CommentAttachmentResponse
+type CommentAttachmentResponse struct {
+	// example: 1
+	ID        uint   `json:"id"`
+	// example: 3
+	CommentID uint   `json:"comment_id"`
+	// ключ файла в хранилище
+	// example: files/5e884898da28047151d0e56f8dc62927e7c8e9f1b2a3d4c5b6a7980f1e2d3c4b.jpg
+	URL       string `json:"url"`
+	// SHA-256 содержимого; по нему файл можно прикрепить повторно без загрузки (поле hash формы).
+	// Пусто у вложений, загруженных до дедупликации
+	// example: 5e884898da28047151d0e56f8dc62927e7c8e9f1b2a3d4c5b6a7980f1e2d3c4b
+	Hash      string `json:"hash"`
+	// исходное имя файла (очищенное от пути и служебных символов)
+	// example: repaired_wall.jpg
+	FileName  string `json:"filename"`
+	// размер файла, байт
+	// example: 1048576
+	Size      int64  `json:"size"`
+	// MIME-тип, определённый по содержимому файла
+	// example: image/jpeg
+	ContentType string `json:"content_type"`
+	// короткоживущая подписанная ссылка на файл (только в ответах эндпоинтов вложений)
+	// example: /api/comment-attachments/1/download?expires=1760456330&signature=Zk9v...
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func NewCommentAttachmentHandler(db *gorm.DB, store storage.Storage, policy upload.Policy, signer *signedurl.Signer, photos exif.Options) *CommentAttachmentHandler {
+	return &CommentAttachmentHandler{db: db, store: store, policy: policy, signer: signer, photos: photos}
+}
+
+// toResponse дополняет ответ подписанной ссылкой на скачивание.
+func (h *CommentAttachmentHandler) toResponse(a models.CommentAttachment) CommentAttachmentResponse {
+	resp := toCommentAttachmentResponse(a)
+	resp.DownloadURL = h.signer.Sign(fmt.Sprintf("/api/comment-attachments/%d/download", a.ID))
+	return resp
+}
+
+func toCommentAttachmentResponse(a models.CommentAttachment) CommentAttachmentResponse {
+	return CommentAttachmentResponse{
+		ID:        a.ID,
+		CommentID: a.CommentID,
+		URL:       a.URL,
+		Hash:      a.Hash,
+		FileName:  attachmentFilename(a.FileName, a.URL),
+		Size:      a.Size,
+		ContentType: a.ContentType,
+	}
+}
+
+// canManageCommentAttachments: прикреплять и удалять файлы может автор комментария,
+// а также observer и manager.
+func canManageCommentAttachments(c *fiber.Ctx, comment models.Comment) bool {
+	if role, _ := c.Locals("role").(string); role == "observer" || role == "manager" {
+		return true
+	}
+	uid, ok := c.Locals("user_id").(uint)
+	return ok && uid == comment.CreatedByPersonID
+}
+
+// UploadCommentAttachment загружает файл вложения для комментария.
+// @Summary     Upload comment attachment
+// @Description Upload a file for a specific comment. Allowed for the comment author and for observer and manager roles. The file type is detected from its content and must be in the allow-list; images and other files have separate size limits. Files are stored once per content (SHA-256): instead of uploading a file again, pass the hash of a file already on the server.
+// @Tags        comment-attachments
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id    path      int     true  "Comment ID"
+// @Param       file      formData  file    false  "File to upload (or hash)"
+// @Param       hash      formData  string  false  "SHA-256 of a file already on the server, instead of file"
+// @Param       filename  formData  string  false  "File name for an attachment created by hash"
+// @Success     201  {object}  CommentAttachmentResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "comment or file with this hash not found"
+// @Failure     413  {object}  common.ErrorResponse  "file is too large"
+// @Failure     415  {object}  common.ErrorResponse  "unsupported file type"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/{id}/attachments [post]
+func (h *CommentAttachmentHandler) UploadCommentAttachment(c *fiber.Ctx) error {
+	commentID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	var comment models.Comment
+	if err := h.db.First(&comment, commentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	if !canManageCommentAttachments(c, comment) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "only the comment author can attach files"})
+	}
+
+	// файл из запроса или уже загруженный файл по хэшу; ссылка на файл и вложение
+	// создаются в одной транзакции
+	var stored storedFile
+	var attachment models.CommentAttachment
+	var saveErr error
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if stored, err = storeFormFile(c, tx, h.store, h.policy); err != nil {
+			return err
+		}
+		attachment = models.CommentAttachment{
+			CommentID:   comment.ID,
+			URL:         stored.Key,
+			Hash:        stored.Hash,
+			FileName:    stored.FileName,
+			Size:        stored.Size,
+			ContentType: stored.ContentType,
+		}
+		saveErr = tx.Create(&attachment).Error
+		return saveErr
+	})
+	if err != nil {
+		if stored.New {
+			if err := dropStoredFile(c.UserContext(), h.db, h.store, stored.Hash, stored.Key); err != nil {
+				log.Warn().Err(err).Str("hash", stored.Hash).Msg("failed to remove stored file")
+			}
+		}
+		if saveErr != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save attachment"})
+		}
+		return uploadError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(h.toResponse(attachment))
+}
+
+// GetCommentAttachments возвращает список вложений комментария.
+// @Summary     List comment attachments
+// @Description Get all attachments for a specific comment. Each attachment carries a short-lived signed download_url.
+// @Tags        comment-attachments
+// @Accept      json
+// @Produce     json
+// @Param       id  path  int  true  "Comment ID"
+// @Success     200  {array}   CommentAttachmentResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/{id}/attachments [get]
+func (h *CommentAttachmentHandler) GetCommentAttachments(c *fiber.Ctx) error {
+	commentID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	var comment models.Comment
+	if err := h.db.Preload("Attachments").First(&comment, commentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := make([]CommentAttachmentResponse, 0, len(comment.Attachments))
+	for _, a := range comment.Attachments {
+		resp = append(resp, h.toResponse(a))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetCommentAttachment возвращает вложение комментария по ID.
+// @Summary     Get comment attachment
+// @Description Get comment attachment by attachment ID, with a short-lived signed download_url
+// @Tags        comment-attachments
+// @Accept      json
+// @Produce     json
+// @Param       id  path  int  true  "Attachment ID"
+// @Success     200  {object}  CommentAttachmentResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comment-attachments/{id} [get]
+func (h *CommentAttachmentHandler) GetCommentAttachment(c *fiber.Ctx) error {
+	attachment, _, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(h.toResponse(attachment))
+}
+
+// DownloadCommentAttachment отдаёт файл вложения комментария.
+// @Summary     Download comment attachment
+// @Description Download the file of a comment attachment. Requires a Bearer token or a valid signed link (download_url from the attachment response: expires and signature query params). With EXIF_STRIP enabled, JPEG and HEIC photos are served without EXIF metadata (orientation is kept).
+// @Tags        comment-attachments
+// @Produce     octet-stream
+// @Param       id         path   int     true   "Attachment ID"
+// @Param       expires    query  int     false  "Signed link expiry (unix seconds)"
+// @Param       signature  query  string  false  "Signed link signature"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "link has expired or signature is invalid"
+// @Failure     404  {object}  common.ErrorResponse  "attachment or file not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comment-attachments/{id}/download [get]
+func (h *CommentAttachmentHandler) DownloadCommentAttachment(c *fiber.Ctx) error {
+	attachment, _, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+
+	filename := attachmentFilename(attachment.FileName, attachment.URL)
+	if h.photos.Strip {
+		return sendStoredWithoutExif(c, h.store, attachment.URL, filename)
+	}
+	return sendStored(c, h.store, attachment.URL, filename)
+}
+
+// DeleteCommentAttachment удаляет вложение комментария по ID.
+// @Summary     Delete comment attachment
+// @Description Delete a comment attachment. Its file is removed from storage once no other attachment references it. Allowed for the comment author and for observer and manager roles.
+// @Tags        comment-attachments
+// @Accept      json
+// @Produce     json
+// @Param       id  path  int  true  "Attachment ID"
+// @Success     200  {object}  map[string]string  "attachment deleted successfully"
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comment-attachments/{id} [delete]
+func (h *CommentAttachmentHandler) DeleteCommentAttachment(c *fiber.Ctx) error {
+	attachment, comment, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+
+	if !canManageCommentAttachments(c, comment) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "only the comment author can delete attachments"})
+	}
+
+	// delete file from db, затем из хранилища — если на файл больше никто не ссылается
+	var files releasedFiles
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&attachment).Error; err != nil {
+			return err
+		}
+		var err error
+		files, err = releaseFiles(tx, commentAttachmentFiles([]models.CommentAttachment{attachment}))
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete attachment"})
+	}
+	removeReleased(c.UserContext(), h.db, h.store, files)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "attachment deleted successfully"})
+}
+
+// findAttachment загружает вложение по :id и его комментарий и проверяет доступ к дефекту.
+// Вложения комментариев из корзины не отдаются.
+func (h *CommentAttachmentHandler) findAttachment(c *fiber.Ctx) (models.CommentAttachment, models.Comment, *fiber.Error) {
+	var attachment models.CommentAttachment
+	var comment models.Comment
+
+	attachmentID, err := c.ParamsInt("id")
+	if err != nil {
+		return attachment, comment, fiber.NewError(fiber.StatusBadRequest, "invalid attachment id")
+	}
+
+	var defect models.Defect
+	err = h.db.First(&attachment, attachmentID).Error
+	if err == nil {
+		err = h.db.First(&comment, attachment.CommentID).Error
+	}
+	if err == nil {
+		err = h.db.Unscoped().First(&defect, comment.DefectID).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return attachment, comment, fiber.NewError(fiber.StatusNotFound, "attachment not found")
+	}
+	if err != nil {
+		return attachment, comment, fiber.NewError(fiber.StatusInternalServerError, "database error")
+	}
+	if !canAccessDefect(c, defect) {
+		return attachment, comment, fiber.NewError(fiber.StatusNotFound, "attachment not found")
+	}
+	return attachment, comment, nil
+}
+
+// commentAttachmentFiles ссылки вложений на файлы для releaseFiles.
+func commentAttachmentFiles(attachments []models.CommentAttachment) []attachmentFile {
+	files := make([]attachmentFile, 0, len(attachments))
+	for _, a := range attachments {
+		files = append(files, attachmentFile{Key: a.URL, Hash: a.Hash})
+	}
+	return files
+}
+
+func toCommentAttachmentResponses(attachments []models.CommentAttachment) []CommentAttachmentResponse {
+	resp := make([]CommentAttachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		resp = append(resp, toCommentAttachmentResponse(a))
+	}
+	return resp
+}
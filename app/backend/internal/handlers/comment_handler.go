@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/Quasar777/buildefect/app/backend/internal/common"
 )
 
@@ -16,10 +19,13 @@ var _ = common.ErrorResponse{} // костыль для swagger: без этой
 
 type CommentHandler struct {
 	db *gorm.DB
+	// сколько времени после создания автор может редактировать комментарий, 0 — без ограничения
+	editWindow time.Duration
+	store      storage.Storage
 }
 
-func NewCommentHandler(db *gorm.DB) *CommentHandler {
-	return &CommentHandler{db: db}
+func NewCommentHandler(db *gorm.DB, editWindow time.Duration, store storage.Storage) *CommentHandler {
+	return &CommentHandler{db: db, editWindow: editWindow, store: store}
 }
 
 // CreateCommentRequest описывает тело запроса для создания комментария
@@ -29,6 +35,9 @@ type CreateCommentRequest struct {
     DefectID uint   `json:"defect_id"`
     // example: Found a broken window on 3rd floor
     Text     string `json:"text"`
+    // ID комментария того же дефекта, на который это ответ
+    // example: 5
+    ParentID *uint  `json:"parent_id"`
 }
 
 // CommentResponse описывает комментарий
@@ -38,27 +47,127 @@ type CommentResponse struct {
     ID        uint      `json:"id"`
     // example: 1
     DefectID  uint      `json:"defect_id"`
+    // null для комментария верхнего уровня
+    // example: 5
+    ParentID  *uint     `json:"parent_id"`
     // example: 2025-10-11T14:00:00Z
     CreatedAt time.Time `json:"created_at"`
     // example: 2
-    CreatedBy uint      `json:"created_by"`
+    CreatedByPersonID uint       `json:"created_by_person_id"`
+    CreatedBy         SimpleUser `json:"created_by"`
     // example: Broken glass needs replacement
     Text      string    `json:"text"`
+    // время последней правки, null если комментарий не редактировался
+    // example: 2025-10-11T14:05:00Z
+    EditedAt  *time.Time `json:"edited_at"`
+    Attachments []CommentAttachmentResponse `json:"attachments"`
+    // пользователи, упомянутые в тексте через @login
+    Mentions    []SimpleUser                `json:"mentions"`
+    // ответы, старые сначала; заполняется только в списке GET /api/comments
+    Replies     []CommentResponse           `json:"replies,omitempty"`
+}
+
+// UpdateCommentRequest описывает тело запроса для редактирования комментария
+// swagger:model UpdateCommentRequest
+type UpdateCommentRequest struct {
+    // example: Broken glass on the 3rd floor needs replacement
+    Text string `json:"text"`
+}
+
+// CommentRevisionResponse описывает предыдущую версию комментария
+// swagger:model CommentRevisionResponse
+type CommentRevisionResponse struct {
+    // example: 1
+    ID         uint      `json:"id"`
+    // example: 1
+    CommentID  uint      `json:"comment_id"`
+    // example: Broken glass needs replacement
+    Text       string    `json:"text"`
+    // когда эта версия была написана
+    // example: 2025-10-11T14:00:00Z
+    WrittenAt  time.Time `json:"written_at"`
+    // когда её заменили новой
+    // example: 2025-10-11T14:05:00Z
+    ReplacedAt time.Time `json:"replaced_at"`
+    // example: 2
+    EditedBy   uint      `json:"edited_by"`
 }
 
 func CreateResponseComment(comment models.Comment) CommentResponse {
+	mentions := make([]SimpleUser, 0, len(comment.Mentions))
+	for _, m := range comment.Mentions {
+		mentions = append(mentions, toSimpleUser(m.User))
+	}
 	return CommentResponse{
 		ID:        comment.ID,
 		DefectID:  comment.DefectID,
+		ParentID:  comment.ParentID,
 		CreatedAt: comment.CreatedAt,
-		CreatedBy: comment.CreatedByPersonID,
+		CreatedByPersonID: comment.CreatedByPersonID,
+		CreatedBy: toSimpleUser(comment.CreatedBy),
 		Text:      comment.Text,
+		EditedAt:  comment.EditedAt,
+		Attachments: toCommentAttachmentResponses(comment.Attachments),
+		Mentions:  mentions,
+	}
+}
+
+// preloadComment подгружает связи, нужные для CommentResponse. Каждая связь — один
+// запрос на всю выборку, так что список комментариев не даёт N+1.
+func preloadComment(db *gorm.DB) *gorm.DB {
+	// автор и упомянутые пользователи могли уйти в корзину, комментарий всё равно показываем
+	return db.Preload("CreatedBy", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Attachments").
+		Preload("Mentions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("comment_mentions.id asc")
+		}).
+		Preload("Mentions.User", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		})
+}
+
+// defectVisible проверяет, что дефект существует и его комментарии видны вызывающему:
+// комментарии дефекта из корзины видны только вместе с ним (см. canAccessDefect).
+func (h *CommentHandler) defectVisible(c *fiber.Ctx, defectID uint) (bool, error) {
+	var defect models.Defect
+	err := h.db.Unscoped().First(&defect, defectID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return canAccessDefect(c, defect), nil
+}
+
+// mentionsError превращает ошибку разбора упоминаний в 400.
+func mentionsError(err error) error {
+	var unknown *unknownMentionsError
+	if errors.As(err, &unknown) {
+		return fiber.NewError(fiber.StatusBadRequest, unknown.Error())
+	}
+	return err
+}
+
+// createComment сохраняет комментарий вместе с его упоминаниями; вызывается внутри транзакции.
+// Неизвестный @login — ошибка 400 (*fiber.Error). Через неё создаются и комментарии
+// к смене статуса, чтобы упоминания в них работали так же, как в POST /api/comments.
+func createComment(tx *gorm.DB, comment *models.Comment) error {
+	mentioned, err := resolveMentions(tx, comment.Text)
+	if err != nil {
+		return mentionsError(err)
+	}
+	if err := tx.Create(comment).Error; err != nil {
+		return err
 	}
+	return syncMentions(tx, comment.ID, mentioned)
 }
 
 // CreateComment создаёт новый комментарий к дефекту
 // @Summary     Create a comment
-// @Description Create a new comment for a specific defect. Requires authentication.
+// @Description Create a new comment for a specific defect. Requires authentication. Set parent_id to reply to another comment of the same defect. Every @login in the text must be an existing user; mentioned users are listed in the response.
 // @Tags        comments
 // @Accept      json
 // @Produce     json
@@ -87,52 +196,168 @@ func (h *CommentHandler) CreateComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
+	if req.ParentID != nil {
+		var parent models.Comment
+		if err := h.db.First(&parent, *req.ParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "parent comment not found"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+		}
+		if parent.DefectID != req.DefectID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "parent comment belongs to another defect"})
+		}
+	}
+
 	userID := c.Locals("user_id").(uint)
 
 	comment := models.Comment{
 		DefectID:          req.DefectID,
+		ParentID:          req.ParentID,
 		Text:              req.Text,
 		CreatedAt:         time.Now(),
 		CreatedByPersonID: userID,
 	}
 
-	if err := h.db.Create(&comment).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return createComment(tx, &comment)
+	})
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create comment"})
 	}
 
+	if err := preloadComment(h.db).First(&comment, comment.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(CreateResponseComment(comment))
 }
 
+// Параметры фильтрации списка комментариев (кроме обязательного defect_id).
+var commentFilterParams = []string{"created_by_id", "created_from", "created_to"}
+
+// applyCommentFilters применяет к запросу по комментариям фильтры из query-параметров
+// (см. commentFilterParams).
+func applyCommentFilters(c *fiber.Ctx, q *gorm.DB) (*gorm.DB, error) {
+	if cb := c.Query("created_by_id"); cb != "" {
+		cid, err := strconv.ParseUint(cb, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid created_by_id")
+		}
+		q = q.Where("comments.created_by_person_id = ?", uint(cid))
+	}
+	return applyDateRange(c, q, "comments.created_at", "created_from", "created_to")
+}
+
 // GetComments возвращает список комментариев для дефекта
 // @Summary     List comments
-// @Description Get all comments for a specific defect
+// @Description Get comment threads for a specific defect. Top-level comments are paged (offset or keyset cursor on created_at, id), sorted by sort and filtered by author and creation date; total counts matching top-level comments. Each of them carries all its replies nested in replies, oldest first. Replies to a deleted comment are hidden together with it.
 // @Tags        comments
 // @Accept      json
 // @Produce     json
-// @Param       defect_id  query     int  true  "Defect ID"
-// @Success     200  {array}   CommentResponse
+// @Param       defect_id      query     int     true   "Defect ID"
+// @Param       created_by_id  query     int     false  "Filter by author user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       sort           query     string  false  "created_at (oldest first) or -created_at (newest first, default)"
+// @Param       limit          query     int     false  "Limit number of results (default 100)"
+// @Param       offset         query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor         query     string  false  "next_cursor from the previous page (not combinable with offset, keep the same sort)"
+// @Success     200  {object}  ListResponse[CommentResponse]
 // @Failure     400  {object}  common.ErrorResponse
-// @Failure     404  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "defect not found or in trash"
 // @Failure     500  {object}  common.ErrorResponse
 // @Router      /api/comments [get]
 func (h *CommentHandler) GetComments(c *fiber.Ctx) error {
-	var comments []models.Comment
+	if err := checkQueryParams(c, append(commentFilterParams, "defect_id", "sort", "limit", "offset", "cursor")...); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	defectID := c.Query("defect_id")
-	query := h.db
-	if defectID != "" {
-		query = query.Where("defect_id = ?", defectID)
-	} else {
+	if c.Query("defect_id") == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "defect_id parameter is required. Send it in URL params"})
 	}
+	defectID, err := strconv.ParseUint(c.Query("defect_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid defect_id"})
+	}
+	visible, err := h.defectVisible(c, uint(defectID))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if !visible {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
+	}
+
+	desc := true
+	switch sort := c.Query("sort"); sort {
+	case "", "-created_at":
+	case "created_at":
+		desc = false
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("unknown sort %q, use created_at or -created_at", sort)})
+	}
+
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Session: запрос переиспользуется для подсчёта total и выборки страницы
+	query := h.db.Model(&models.Comment{}).Where("comments.defect_id = ?", defectID).Session(&gorm.Session{})
 
-	if err := query.Order("created_at desc").Find(&comments).Error; err != nil {
+	// страницы строятся по комментариям верхнего уровня, ответы идут вместе с ними
+	roots, err := applyCommentFilters(c, query.Where("comments.parent_id IS NULL"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	roots = roots.Session(&gorm.Session{})
+
+	var comments []models.Comment
+	total, hasMore, err := findPage(roots, keysetOrder(preloadComment(roots), "comments", desc, page), page, &comments)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	resp := make([]CommentResponse, 0, len(comments))
+	// ответы загружаются по уровням вложенности: один запрос (плюс preload) на уровень,
+	// только для веток текущей страницы
+	children := make(map[uint][]models.Comment)
+	parentIDs := make([]uint, 0, len(comments))
 	for _, comment := range comments {
-		resp = append(resp, CreateResponseComment(comment))
+		parentIDs = append(parentIDs, comment.ID)
+	}
+	for len(parentIDs) > 0 {
+		var replies []models.Comment
+		if err := preloadComment(query).Where("comments.parent_id IN ?", parentIDs).
+			Order("comments.created_at asc, comments.id asc").Find(&replies).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+		}
+		parentIDs = parentIDs[:0]
+		for _, r := range replies {
+			children[*r.ParentID] = append(children[*r.ParentID], r)
+			parentIDs = append(parentIDs, r.ID)
+		}
+	}
+
+	var thread func(comment models.Comment) CommentResponse
+	thread = func(comment models.Comment) CommentResponse {
+		resp := CreateResponseComment(comment)
+		resp.Replies = make([]CommentResponse, 0, len(children[comment.ID]))
+		for _, r := range children[comment.ID] {
+			resp.Replies = append(resp.Replies, thread(r))
+		}
+		return resp
+	}
+
+	resp := ListResponse[CommentResponse]{Items: make([]CommentResponse, 0, len(comments)), Total: total}
+	for _, comment := range comments {
+		resp.Items = append(resp.Items, thread(comment))
+	}
+	if hasMore {
+		last := comments[len(comments)-1]
+		resp.NextCursor = nextCursor(true, last.CreatedAt, last.ID)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -157,21 +382,252 @@ func (h *CommentHandler) GetComment(c *fiber.Ctx) error {
 	}
 
 	var comment models.Comment
-	result := h.db.Preload("CreatedBy").Preload("Attachments").First(&comment, id)
+	result := preloadComment(h.db).First(&comment, id)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
 	}
 	if result.Error != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
+	visible, err := h.defectVisible(c, comment.DefectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if !visible {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	}
 
 	resp := CreateResponseComment(comment)
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
-// DeleteComment удаляет комментарий по ID (только для пользователя с ролью "observer")
+// UpdateComment редактирует текст комментария, сохраняя предыдущую версию
+// @Summary     Edit a comment
+// @Description Edit the text of a comment. Only the author can edit, and only within the configured edit window after creation (COMMENT_EDIT_WINDOW). The previous text is kept as a revision. Mentions are re-parsed from the new text.
+// @Tags        comments
+// @Accept      json
+// @Produce     json
+// @Param       id       path      int                   true  "Comment ID"
+// @Param       comment  body      UpdateCommentRequest  true  "New text"
+// @Success     200  {object}  CommentResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "not the author or edit window has expired"
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/{id} [patch]
+func (h *CommentHandler) UpdateComment(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req UpdateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "text is required"})
+	}
+
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	var comment models.Comment
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// блокируем строку, чтобы параллельные правки не потеряли ревизию
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "comment not found")
+			}
+			return err
+		}
+		if comment.CreatedByPersonID != uid {
+			return fiber.NewError(fiber.StatusForbidden, "only the author can edit the comment")
+		}
+		now := time.Now()
+		if h.editWindow > 0 && now.Sub(comment.CreatedAt) > h.editWindow {
+			return fiber.NewError(fiber.StatusForbidden, "edit window has expired")
+		}
+		if req.Text == comment.Text {
+			return nil
+		}
+		mentioned, err := resolveMentions(tx, req.Text)
+		if err != nil {
+			return mentionsError(err)
+		}
+
+		writtenAt := comment.CreatedAt
+		if comment.EditedAt != nil {
+			writtenAt = *comment.EditedAt
+		}
+		revision := models.CommentRevision{
+			CommentID:        comment.ID,
+			Text:             comment.Text,
+			WrittenAt:        writtenAt,
+			ReplacedAt:       now,
+			EditedByPersonID: uid,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		comment.Text = req.Text
+		comment.EditedAt = &now
+		if err := tx.Model(&comment).Select("text", "edited_at").Updates(&comment).Error; err != nil {
+			return err
+		}
+		return syncMentions(tx, comment.ID, mentioned)
+	})
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update comment"})
+	}
+
+	if err := preloadComment(h.db).First(&comment, comment.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(CreateResponseComment(comment))
+}
+
+// GetCommentRevisions возвращает предыдущие версии комментария
+// @Summary     List comment revisions
+// @Description Get previous versions of a comment, oldest first. The current text is in the comment itself.
+// @Tags        comments
+// @Accept      json
+// @Produce     json
+// @Param       id  path  int  true  "Comment ID"
+// @Success     200  {array}   CommentRevisionResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Router      /api/comments/{id}/revisions [get]
+func (h *CommentHandler) GetCommentRevisions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var comment models.Comment
+	result := h.db.First(&comment, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	}
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	visible, err := h.defectVisible(c, comment.DefectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	if !visible {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	}
+
+	var revisions []models.CommentRevision
+	if err := h.db.Where("comment_id = ?", comment.ID).Order("replaced_at asc, id asc").Find(&revisions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := make([]CommentRevisionResponse, 0, len(revisions))
+	for _, r := range revisions {
+		resp = append(resp, CommentRevisionResponse{
+			ID:         r.ID,
+			CommentID:  r.CommentID,
+			Text:       r.Text,
+			WrittenAt:  r.WrittenAt,
+			ReplacedAt: r.ReplacedAt,
+			EditedBy:   r.EditedByPersonID,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// GetUserMentions возвращает комментарии, в которых упомянут пользователь
+// @Summary     List user mentions
+// @Description Get comments that mention the user via @login, newest first, page by page (offset or keyset cursor on created_at, id). Users read their own mentions (id may be "me"); observer and manager roles may read anyone's.
+// @Tags        comments
+// @Accept      json
+// @Produce     json
+// @Param       id      path      string  true   "User ID or me"
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object}  ListResponse[CommentResponse]
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "mentions of another user"
+// @Failure     404  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/users/{id}/mentions [get]
+func (h *CommentHandler) GetUserMentions(c *fiber.Ctx) error {
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	id := int(uid)
+	if c.Params("id") != "me" {
+		var err error
+		if id, err = c.ParamsInt("id"); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+	}
+	// чужие упоминания видят только наблюдатели и менеджеры
+	if role, _ := c.Locals("role").(string); uint(id) != uid && role != "observer" && role != "manager" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "insufficient permissions"})
+	}
+
+	var user models.User
+	result := h.db.First(&user, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+	}
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// упоминания в комментариях дефектов из корзины не показываются
+	query := h.db.Model(&models.Comment{}).
+		Joins("JOIN comment_mentions ON comment_mentions.comment_id = comments.id").
+		Joins("JOIN defects ON defects.id = comments.defect_id AND defects.deleted_at IS NULL").
+		Where("comment_mentions.user_id = ?", user.ID).
+		Session(&gorm.Session{})
+
+	var comments []models.Comment
+	total, hasMore, err := findPage(query, keysetOrder(preloadComment(query), "comments", true, page), page, &comments)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[CommentResponse]{Items: make([]CommentResponse, 0, len(comments)), Total: total}
+	for _, comment := range comments {
+		resp.Items = append(resp.Items, CreateResponseComment(comment))
+	}
+	if hasMore {
+		last := comments[len(comments)-1]
+		resp.NextCursor = nextCursor(true, last.CreatedAt, last.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// DeleteComment перемещает комментарий в корзину (только для пользователя с ролью "observer")
 // @Summary     Delete a comment
-// @Description Delete a comment by ID. Only users with role "observer" are allowed.
+// @Description Soft-delete a comment by ID. Only users with role "observer" are allowed. The comment can be restored or purged from trash; attachment files are kept until the comment is purged.
 // @Tags        comments
 // @Accept      json
 // @Produce     json
@@ -204,9 +660,171 @@ func (h *CommentHandler) DeleteComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	if err := h.db.Delete(&comment).Error; err != nil {
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	if err := softDelete(h.db, &comment, uid); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete comment"})
 	}
 
 	return c.Status(fiber.StatusOK).SendString("Successfully deleted comment with id " + strconv.Itoa(int(comment.ID)))
-}
\ No newline at end of file
+}
+
+// DeletedCommentResponse описывает комментарий в корзине
+// swagger:model DeletedCommentResponse
+type DeletedCommentResponse struct {
+	CommentResponse
+	DeletionInfo
+}
+
+// GetDeletedComments возвращает комментарии из корзины
+// @Summary     List deleted comments
+// @Description Get soft-deleted comments page by page, most recently deleted first (offset or keyset cursor on deleted_at, id). Optional filter by defect.
+// @Tags        comments
+// @Accept      json
+// @Produce     json
+// @Param       defect_id  query     int  false  "Defect ID"
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object}  ListResponse[DeletedCommentResponse]
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/trash [get]
+func (h *CommentHandler) GetDeletedComments(c *fiber.Ctx) error {
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	query := trashScope(h.db.Model(&models.Comment{}))
+	if defectID := c.Query("defect_id"); defectID != "" {
+		query = query.Where("defect_id = ?", defectID)
+	}
+
+	var comments []models.Comment
+	q := trashOrder(preloadComment(query.Session(&gorm.Session{})), "comments", page)
+	total, hasMore, err := findPage(query.Session(&gorm.Session{}), q, page, &comments)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[DeletedCommentResponse]{Items: make([]DeletedCommentResponse, 0, len(comments)), Total: total}
+	for _, comment := range comments {
+		resp.Items = append(resp.Items, DeletedCommentResponse{
+			CommentResponse: CreateResponseComment(comment),
+			DeletionInfo:    toDeletionInfo(comment.DeletedAt, comment.DeletedByPersonID),
+		})
+	}
+	if hasMore {
+		last := comments[len(comments)-1]
+		resp.NextCursor = nextCursor(true, last.DeletedAt.Time, last.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreComment восстанавливает комментарий из корзины
+// @Summary     Restore a comment
+// @Description Restore a soft-deleted comment
+// @Tags        comments
+// @Accept      json
+// @Produce     json
+// @Param       id  path  int  true  "Comment ID"
+// @Success     200  {object}  CommentResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted comment not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/{id}/restore [post]
+func (h *CommentHandler) RestoreComment(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var comment models.Comment
+	if err := findDeleted(preloadComment(h.db), &comment, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	if err := restoreDeleted(h.db, &comment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to restore comment"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(CreateResponseComment(comment))
+}
+
+// PurgeComment окончательно удаляет комментарий из корзины вместе с вложениями
+// @Summary     Purge a comment
+// @Description Permanently delete a soft-deleted comment with its attachments, revisions and mentions. Its replies move up to the purged comment's parent. Attachment files no longer referenced by other attachments are removed after the transaction commits.
+// @Tags        comments
+// @Accept      json
+// @Produce     plain
+// @Param       id  path  int  true  "Comment ID"
+// @Success     200  {string}  string  "Permanently deleted comment with id {id}"
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted comment not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/comments/{id}/purge [delete]
+func (h *CommentHandler) PurgeComment(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var comment models.Comment
+	if err := findDeleted(h.db, &comment, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var files releasedFiles
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var attachments []models.CommentAttachment
+		if err := tx.Where("comment_id = ?", comment.ID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentAttachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		// ответы (в том числе из корзины) поднимаются на уровень выше, ветка не теряется
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("parent_id = ?", comment.ID).
+			Update("parent_id", comment.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&comment).Error; err != nil {
+			return err
+		}
+		var err error
+		files, err = releaseFiles(tx, commentAttachmentFiles(attachments))
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to purge comment"})
+	}
+	removeReleased(c.UserContext(), h.db, h.store, files)
+
+	return c.Status(fiber.StatusOK).SendString("Permanently deleted comment with id " + strconv.Itoa(int(comment.ID)))
+}
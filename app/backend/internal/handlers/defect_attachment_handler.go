@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"math"
 	"time"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
 	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 	"github.com/Quasar777/buildefect/app/backend/internal/common"
 )
@@ -15,7 +23,12 @@ import (
 var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
 
 type DefectAttachmentHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	store    storage.Storage
+	policy   upload.Policy
+	signer   *signedurl.Signer
+	previews *preview.Worker
+	photos   exif.Options
 }
 
 // DefectAttachmentResponse описывает файл вложения дефекта.
@@ -25,25 +38,219 @@ type DefectAttachmentResponse struct {
     ID       uint   `json:"id"`
     // example: 2
     DefectID uint   `json:"defect_id"`
-    // example: internal/uploads/defect_attachments/1759835216551583000_broken_wall.png
+    // ключ файла в хранилище
+    // example: files/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png
     URL      string `json:"url"`
+    // SHA-256 содержимого; по нему файл можно прикрепить повторно без загрузки (поле hash формы).
+    // Пусто у вложений, загруженных до дедупликации
+    // example: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+    Hash string `json:"hash"`
+    // исходное имя файла (очищенное от пути и служебных символов)
+    // example: broken_wall.png
+    FileName string `json:"filename"`
+    // размер файла, байт
+    // example: 482113
+    Size int64 `json:"size"`
+    // MIME-тип, определённый по содержимому файла
+    // example: image/png
+    ContentType string `json:"content_type"`
+    // короткоживущая подписанная ссылка на файл, работает без Authorization (например, в <img src>)
+    // example: /api/attachments/1/download?expires=1760456330&signature=Zk9v...
+    DownloadURL string `json:"download_url"`
+    // состояние уменьшенных копий: pending, ready, failed или none (не изображение)
+    // example: ready
+    PreviewStatus string `json:"preview_status"`
+    // подписанная ссылка на миниатюру (до 320 px, JPEG), только когда preview_status = ready
+    // example: /api/attachments/1/thumbnail?expires=1760456330&signature=Zk9v...
+    ThumbnailURL string `json:"thumbnail_url,omitempty"`
+    // подписанная ссылка на превью (до 1280 px, JPEG), только когда preview_status = ready
+    // example: /api/attachments/1/preview?expires=1760456330&signature=Zk9v...
+    PreviewURL string `json:"preview_url,omitempty"`
+
+    // метаданные EXIF фото (JPEG и HEIC); null, если их нет
+
+    // время съёмки
+    // example: 2025-10-07T14:03:12+03:00
+    TakenAt *time.Time `json:"taken_at"`
+    // производитель и модель камеры
+    // example: Apple iPhone 14 Pro
+    Device string `json:"device,omitempty"`
+    // ориентация по EXIF (1..8), 0 — не указана
+    // example: 6
+    Orientation int `json:"orientation"`
+    // example: 59.93581
+    Latitude *float64 `json:"latitude"`
+    // example: 30.32612
+    Longitude *float64 `json:"longitude"`
+    // расстояние от места съёмки до здания дефекта, м; null, если у фото или здания нет координат
+    // example: 42
+    DistanceFromBuilding *float64 `json:"distance_from_building"`
+    // фото снято дальше допустимого расстояния от здания (PHOTO_MAX_DISTANCE)
+    // example: false
+    FarFromBuilding bool `json:"far_from_building"`
+}
+
+func NewDefectAttachmentHandler(db *gorm.DB, store storage.Storage, policy upload.Policy, signer *signedurl.Signer, previews *preview.Worker, photos exif.Options) *DefectAttachmentHandler {
+	return &DefectAttachmentHandler{db: db, store: store, policy: policy, signer: signer, previews: previews, photos: photos}
+}
+
+// toResponse собирает ответ; building — здание дефекта для сверки геотега (может быть nil).
+func (h *DefectAttachmentHandler) toResponse(a models.DefectAttachment, building *models.Building) DefectAttachmentResponse {
+	resp := DefectAttachmentResponse{
+		ID:            a.ID,
+		DefectID:      a.DefectID,
+		URL:           a.URL,
+		Hash:          a.Hash,
+		FileName:      attachmentFilename(a.FileName, a.URL),
+		Size:          a.Size,
+		ContentType:   a.ContentType,
+		DownloadURL:   h.signer.Sign(fmt.Sprintf("/api/attachments/%d/download", a.ID)),
+		PreviewStatus: a.PreviewStatus,
+		TakenAt:       a.TakenAt,
+		Device:        a.Device,
+		Orientation:   a.Orientation,
+		Latitude:      a.Latitude,
+		Longitude:     a.Longitude,
+	}
+	if a.Latitude != nil && a.Longitude != nil && building != nil && building.Latitude != nil && building.Longitude != nil {
+		d := math.Round(exif.Distance(*a.Latitude, *a.Longitude, *building.Latitude, *building.Longitude))
+		resp.DistanceFromBuilding = &d
+		resp.FarFromBuilding = d > h.photos.MaxDistance
+	}
+	if a.ThumbnailKey != "" {
+		resp.ThumbnailURL = h.signer.Sign(fmt.Sprintf("/api/attachments/%d/thumbnail", a.ID))
+	}
+	if a.PreviewKey != "" {
+		resp.PreviewURL = h.signer.Sign(fmt.Sprintf("/api/attachments/%d/preview", a.ID))
+	}
+	return resp
+}
+
+// findBuilding здание дефекта для сверки геотега фото; nil, если его не удалось загрузить.
+// Здание может быть в корзине вместе с дефектом.
+func (h *DefectAttachmentHandler) findBuilding(id uint) *models.Building {
+	var building models.Building
+	if err := h.db.Unscoped().First(&building, id).Error; err != nil {
+		return nil
+	}
+	return &building
+}
+
+// newAttachment собирает вложение дефекта для файла stored. Если этот файл уже прикреплён
+// к другому дефекту, метаданные фото и готовые копии берутся оттуда; иначе EXIF читается
+// из файла, а копии строятся заново.
+func (h *DefectAttachmentHandler) newAttachment(c *fiber.Ctx, tx *gorm.DB, defectID uint, stored storedFile) models.DefectAttachment {
+	attachment := models.DefectAttachment{
+		DefectID:      defectID,
+		URL:           stored.Key,
+		Hash:          stored.Hash,
+		FileName:      stored.FileName,
+		Size:          stored.Size,
+		ContentType:   stored.ContentType,
+		PreviewStatus: preview.StatusPending,
+	}
+	if !preview.Supported(stored.ContentType) {
+		attachment.PreviewStatus = preview.StatusNone
+	}
+
+	var same models.DefectAttachment
+	if !stored.New && tx.Where("hash = ?", stored.Hash).Order("id").First(&same).Error == nil {
+		attachment.TakenAt = same.TakenAt
+		attachment.Device = same.Device
+		attachment.Orientation = same.Orientation
+		attachment.Latitude = same.Latitude
+		attachment.Longitude = same.Longitude
+		if same.PreviewStatus == preview.StatusReady {
+			attachment.ThumbnailKey = same.ThumbnailKey
+			attachment.PreviewKey = same.PreviewKey
+			attachment.PreviewStatus = preview.StatusReady
+		}
+		return attachment
+	}
+
+	// метаданные фото: время съёмки, устройство, геотег
+	meta := h.readPhotoMetadata(c, stored)
+	attachment.TakenAt = meta.TakenAt
+	attachment.Device = meta.Device()
+	attachment.Orientation = meta.Orientation
+	attachment.Latitude = meta.Latitude
+	attachment.Longitude = meta.Longitude
+	return attachment
+}
+
+// readPhotoMetadata читает EXIF фото: из загруженного файла или, если файл указан хэшем,
+// из хранилища. Фото без EXIF — обычное дело (скриншоты, пересланные через мессенджеры),
+// поэтому ошибки только логируются.
+func (h *DefectAttachmentHandler) readPhotoMetadata(c *fiber.Ctx, stored storedFile) exif.Metadata {
+	if !exif.Supported(stored.ContentType) {
+		return exif.Metadata{}
+	}
+
+	var r io.ReaderAt
+	if file, err := c.FormFile("file"); err == nil {
+		src, err := file.Open()
+		if err != nil {
+			return exif.Metadata{}
+		}
+		defer src.Close()
+		r = src
+	} else {
+		obj, err := h.store.Get(c.UserContext(), stored.Key)
+		if err != nil {
+			log.Warn().Err(err).Str("key", stored.Key).Msg("failed to open stored file")
+			return exif.Metadata{}
+		}
+		data, err := io.ReadAll(obj.Body)
+		obj.Body.Close()
+		if err != nil {
+			log.Warn().Err(err).Str("key", stored.Key).Msg("failed to read stored file")
+			return exif.Metadata{}
+		}
+		r = bytes.NewReader(data)
+	}
+
+	meta, err := exif.Read(r, stored.Size, stored.ContentType)
+	if err != nil && !errors.Is(err, exif.ErrNoExif) {
+		log.Debug().Err(err).Str("content_type", stored.ContentType).Msg("failed to read photo exif")
+	}
+	return meta
 }
 
-func NewDefectAttachmentHandler(db *gorm.DB) *DefectAttachmentHandler {
-	return &DefectAttachmentHandler{db: db}
+// defectAttachmentFiles ссылки вложений на файлы для releaseFiles.
+func defectAttachmentFiles(attachments []models.DefectAttachment) []attachmentFile {
+	files := make([]attachmentFile, 0, len(attachments))
+	for _, a := range attachments {
+		files = append(files, attachmentFile{Key: a.URL, Hash: a.Hash})
+	}
+	return files
+}
+
+// canAccessDefect решает, может ли вызывающий читать дефект и его файлы. Дефект из корзины
+// доступен только ролям, которые видят корзину; по подписанной ссылке роли нет, и такие
+// дефекты недоступны.
+func canAccessDefect(c *fiber.Ctx, defect models.Defect) bool {
+	if !defect.DeletedAt.Valid {
+		return true
+	}
+	role, _ := c.Locals("role").(string)
+	return role == "observer" || role == "manager"
 }
 
 // UploadDefectAttachment загружает файл вложения для дефекта.
 // @Summary     Upload defect attachment
-// @Description Upload a file for a specific defect. Requires authentication.
+// @Description Upload a file for a specific defect. Requires authentication. The file type is detected from its content and must be in the allow-list (by default JPEG, PNG, WebP, HEIC and PDF); images and other files have separate size limits. Thumbnails and previews of images are generated in the background: the response has preview_status "pending" until they are ready. EXIF of JPEG and HEIC photos (capture time, device, orientation, GPS) is stored with the attachment; photos taken far from the building are flagged with far_from_building. Files are stored once per content (SHA-256): instead of uploading a file again, pass the hash of a file already on the server.
 // @Tags        defect-attachments
 // @Accept      multipart/form-data
 // @Produce     json
 // @Param       id    path      int     true  "Defect ID"
-// @Param       file  formData  file    true  "File to upload"
+// @Param       file      formData  file    false  "File to upload (or hash)"
+// @Param       hash      formData  string  false  "SHA-256 of a file already on the server, instead of file"
+// @Param       filename  formData  string  false  "File name for an attachment created by hash"
 // @Success     201  {object}  DefectAttachmentResponse
 // @Failure     400  {object}  common.ErrorResponse
-// @Failure     404  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "defect or file with this hash not found"
+// @Failure     413  {object}  common.ErrorResponse  "file is too large"
+// @Failure     415  {object}  common.ErrorResponse  "unsupported file type"
 // @Failure     500  {object}  common.ErrorResponse
 // @Security    BearerAuth
 // @Router      /api/defects/{id}/attachments [post]
@@ -61,45 +268,53 @@ func (h *DefectAttachmentHandler) UploadDefectAttachment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	// getting file (formdata)
-	file, err := c.FormFile("file")
+	// файл из запроса или уже загруженный файл по хэшу; ссылка на файл и вложение
+	// создаются в одной транзакции
+	var stored storedFile
+	var attachment models.DefectAttachment
+	var saveErr error
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if stored, err = storeFormFile(c, tx, h.store, h.policy); err != nil {
+			return err
+		}
+		attachment = h.newAttachment(c, tx, uint(defectID), stored)
+		saveErr = tx.Create(&attachment).Error
+		return saveErr
+	})
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file required"})
-	}
-
-	// generating filename with random prefix + filename
-	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-	path := fmt.Sprintf("internal/uploads/defect_attachments/%s", filename)
-
-	// saving file locally
-	if err := c.SaveFile(file, path); err != nil {
-		fmt.Println(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save file"})
+		if stored.New {
+			if err := dropStoredFile(c.UserContext(), h.db, h.store, stored.Hash, stored.Key); err != nil {
+				log.Warn().Err(err).Str("hash", stored.Hash).Msg("failed to remove stored file")
+			}
+		}
+		if saveErr != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save attachment"})
+		}
+		return uploadError(c, err)
 	}
 
-	// saving file in db
-	attachment := models.DefectAttachment{
-		DefectID: uint(defectID),
-		URL:      path,
-	}
-	if err := h.db.Create(&attachment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save attachment"})
+	// миниатюра и превью строятся в фоне, загрузка их не ждёт
+	if attachment.PreviewStatus == preview.StatusPending {
+		h.previews.Enqueue(attachment.ID)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(attachment)
+	return c.Status(fiber.StatusCreated).JSON(h.toResponse(attachment, h.findBuilding(defect.BuildingID)))
 }
 
 // GetDefectAttachments возвращает список вложений дефекта.
 // @Summary     List defect attachments
-// @Description Get all attachments for a specific defect
+// @Description Get all attachments for a specific defect. Each attachment carries a short-lived signed download_url.
 // @Tags        defect-attachments
 // @Accept      json
 // @Produce     json
 // @Param       id  path  int  true  "Defect ID"
 // @Success     200  {array}   DefectAttachmentResponse
 // @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
 // @Failure     404  {object}  common.ErrorResponse
 // @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
 // @Router      /api/defects/{id}/attachments [get]
 func (h *DefectAttachmentHandler) GetDefectAttachments(c *fiber.Ctx) error {
     defectID, err := c.ParamsInt("id")
@@ -120,41 +335,153 @@ func (h *DefectAttachmentHandler) GetDefectAttachments(c *fiber.Ctx) error {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get attachments"})
     }
 
-    return c.Status(fiber.StatusOK).JSON(attachments)
+    building := h.findBuilding(defect.BuildingID)
+    resp := make([]DefectAttachmentResponse, 0, len(attachments))
+    for _, a := range attachments {
+        resp = append(resp, h.toResponse(a, building))
+    }
+
+    return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 // GetDefectAttachment возвращает конкретное вложение дефекта по ID.
 // @Summary     Get defect attachment
-// @Description Get defect attachment by attachment ID
+// @Description Get defect attachment by attachment ID, with a short-lived signed download_url
 // @Tags        defect-attachments
 // @Accept      json
 // @Produce     json
 // @Param       id  path  int  true  "Attachment ID"
 // @Success     200  {object}  DefectAttachmentResponse
 // @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
 // @Failure     404  {object}  common.ErrorResponse
 // @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
 // @Router      /api/attachments/{id} [get]
 func (h *DefectAttachmentHandler) GetDefectAttachment(c *fiber.Ctx) error {
-    attachmentID, err := c.ParamsInt("id")
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid attachment id"})
+    attachment, fe := h.findAttachment(c)
+    if fe != nil {
+        return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
     }
 
-    var attachment models.DefectAttachment
-    if err := h.db.First(&attachment, attachmentID).Error; err != nil {
-        if err == gorm.ErrRecordNotFound {
-            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "attachment not found"})
-        }
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
-    }
+    return c.Status(fiber.StatusOK).JSON(h.toResponse(attachment, h.findBuilding(attachment.Defect.BuildingID)))
+}
 
-    return c.Status(fiber.StatusOK).JSON(attachment)
+// DownloadDefectAttachment отдаёт файл вложения дефекта.
+// @Summary     Download defect attachment
+// @Description Stream the file of a defect attachment. Requires a Bearer token or a valid signed link (download_url from the attachment response: expires and signature query params). With EXIF_STRIP enabled, JPEG and HEIC photos are served without EXIF metadata (orientation is kept).
+// @Tags        defect-attachments
+// @Produce     octet-stream
+// @Param       id         path   int     true   "Attachment ID"
+// @Param       expires    query  int     false  "Signed link expiry (unix seconds)"
+// @Param       signature  query  string  false  "Signed link signature"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "link has expired or signature is invalid"
+// @Failure     404  {object}  common.ErrorResponse  "attachment or file not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/attachments/{id}/download [get]
+func (h *DefectAttachmentHandler) DownloadDefectAttachment(c *fiber.Ctx) error {
+	attachment, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+
+	// inline, чтобы фото открывались в <img> и во вкладке браузера
+	if h.photos.Strip {
+		return sendStoredWithoutExif(c, h.store, attachment.URL, "")
+	}
+	return sendStored(c, h.store, attachment.URL, "")
+}
+
+// DownloadDefectAttachmentThumbnail отдаёт миниатюру фото вложения дефекта.
+// @Summary     Download defect attachment thumbnail
+// @Description Stream the thumbnail (JPEG, at most 320 px on the longer side) of an image attachment. Requires a Bearer token or a valid signed link (thumbnail_url from the attachment response). Returns 404 while the thumbnail is not generated yet (see preview_status).
+// @Tags        defect-attachments
+// @Produce     jpeg
+// @Param       id         path   int     true   "Attachment ID"
+// @Param       expires    query  int     false  "Signed link expiry (unix seconds)"
+// @Param       signature  query  string  false  "Signed link signature"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "link has expired or signature is invalid"
+// @Failure     404  {object}  common.ErrorResponse  "attachment or thumbnail not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/attachments/{id}/thumbnail [get]
+func (h *DefectAttachmentHandler) DownloadDefectAttachmentThumbnail(c *fiber.Ctx) error {
+	attachment, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	if attachment.ThumbnailKey == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "thumbnail not found"})
+	}
+
+	return sendStored(c, h.store, attachment.ThumbnailKey, "")
+}
+
+// DownloadDefectAttachmentPreview отдаёт превью фото вложения дефекта.
+// @Summary     Download defect attachment preview
+// @Description Stream the medium preview (JPEG, at most 1280 px on the longer side) of an image attachment. Requires a Bearer token or a valid signed link (preview_url from the attachment response). Returns 404 while the preview is not generated yet (see preview_status).
+// @Tags        defect-attachments
+// @Produce     jpeg
+// @Param       id         path   int     true   "Attachment ID"
+// @Param       expires    query  int     false  "Signed link expiry (unix seconds)"
+// @Param       signature  query  string  false  "Signed link signature"
+// @Success     200  {file}    file
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse  "link has expired or signature is invalid"
+// @Failure     404  {object}  common.ErrorResponse  "attachment or preview not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/attachments/{id}/preview [get]
+func (h *DefectAttachmentHandler) DownloadDefectAttachmentPreview(c *fiber.Ctx) error {
+	attachment, fe := h.findAttachment(c)
+	if fe != nil {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	if attachment.PreviewKey == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "preview not found"})
+	}
+
+	return sendStored(c, h.store, attachment.PreviewKey, "")
+}
+
+// findAttachment загружает вложение по :id и проверяет доступ к его дефекту.
+func (h *DefectAttachmentHandler) findAttachment(c *fiber.Ctx) (models.DefectAttachment, *fiber.Error) {
+	var attachment models.DefectAttachment
+	var defect models.Defect
+
+	attachmentID, err := c.ParamsInt("id")
+	if err != nil {
+		return attachment, fiber.NewError(fiber.StatusBadRequest, "invalid attachment id")
+	}
+
+	err = h.db.First(&attachment, attachmentID).Error
+	if err == nil {
+		err = h.db.Unscoped().First(&defect, attachment.DefectID).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return attachment, fiber.NewError(fiber.StatusNotFound, "attachment not found")
+	}
+	if err != nil {
+		return attachment, fiber.NewError(fiber.StatusInternalServerError, "database error")
+	}
+	if !canAccessDefect(c, defect) {
+		return attachment, fiber.NewError(fiber.StatusNotFound, "attachment not found")
+	}
+	attachment.Defect = defect
+	return attachment, nil
 }
 
 // DeleteDefectAttachment удаляет вложение дефекта по ID.
 // @Summary     Delete defect attachment
-// @Description Delete a defect attachment by attachment ID
+// @Description Delete a defect attachment by attachment ID. Its file, thumbnail and preview are removed from storage once no other attachment references the same file.
 // @Tags        defect-attachments
 // @Accept      json
 // @Produce     json
@@ -180,16 +507,20 @@ func (h *DefectAttachmentHandler) DeleteDefectAttachment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	// deleting file from disk
-	if err := os.Remove(attachment.URL); err != nil {
-		fmt.Println("failed to remove file:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to remove file"})
-	}
-
-	// delete file from db
-	if err := h.db.Delete(&attachment).Error; err != nil {
+	// delete file from db, затем из хранилища — если на файл больше никто не ссылается
+	var files releasedFiles
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&attachment).Error; err != nil {
+			return err
+		}
+		var err error
+		files, err = releaseFiles(tx, defectAttachmentFiles([]models.DefectAttachment{attachment}))
+		return err
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete attachment"})
 	}
+	removeReleased(c.UserContext(), h.db, h.store, files)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "attachment deleted successfully"})
 }
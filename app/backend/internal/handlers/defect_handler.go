@@ -2,23 +2,31 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/history"
 	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/Quasar777/buildefect/app/backend/internal/common"
 )
 
 var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
 
 type DefectHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	workflow *workflow.Workflow
+	store    storage.Storage
 }
 
-func NewDefectHandler(db *gorm.DB) *DefectHandler {
-	return &DefectHandler{db: db}
+func NewDefectHandler(db *gorm.DB, wf *workflow.Workflow, store storage.Storage) *DefectHandler {
+	return &DefectHandler{db: db, workflow: wf, store: store}
 }
 
 // CreateDefectRequest описывает тело запроса для создания дефекта.
@@ -37,8 +45,6 @@ type CreateDefectRequest struct {
     // example: 2025-12-31 23:59:59
     // deadline in layout "2006-01-02 15:04:05"
     Deadline            string `json:"deadline"`               // optional, format: "2006-01-02 15:04:05"
-    // example: new
-    Status              string `json:"status"`                 // optional, default "new"
 }
 
 // SimpleUser краткая модель пользователя для вложенных сущностей.
@@ -78,6 +84,20 @@ type DefectResponse struct {
 	Responsible         *SimpleUser    `json:"responsible,omitempty"`
 	Deadline            *time.Time     `json:"deadline,omitempty"`
 	Status              string         `json:"status"`
+	Metrics             *DefectMetrics `json:"metrics,omitempty"`
+}
+
+// DefectMetrics описывает сроки работы с дефектом, посчитанные по интервалам статусов.
+// swagger:model DefectMetrics
+type DefectMetrics struct {
+	// часы в каждом статусе (для текущего статуса — до текущего момента)
+	TimeInStatusHours  map[string]float64 `json:"time_in_status_hours"`
+	// часы от создания до первого перехода из начального статуса схемы, null пока дефект не взят в работу
+	// example: 5.25
+	FirstResponseHours *float64           `json:"first_response_hours"`
+	// часы от создания до (последнего) перехода в статус closed схемы, null если дефект не закрыт
+	// example: 96
+	CloseHours         *float64           `json:"close_hours"`
 }
 
 // UpdateStatusReq описывает тело запроса для изменения статуса дефекта.
@@ -86,6 +106,122 @@ type UpdateStatusReq struct {
 	// example: in_progress
     // Возможные значения: "new", "in_progress", "review", "closed" (зависит от вашей логики)
 	Status string `json:"status"`
+	// example: Работы завершены, прошу принять
+	// Комментарий к смене статуса; обязателен для переходов с require_comment
+	Comment string `json:"comment"`
+}
+
+// UpdateDefectRequest описывает тело запроса для частичного обновления дефекта.
+// Все поля необязательны: изменяются только переданные.
+// swagger:model UpdateDefectRequest
+type UpdateDefectRequest struct {
+    // example: 1
+    BuildingID          *uint   `json:"building_id"`
+    // example: Трещина в несущей стене
+    Title               *string `json:"title"`
+    // example: Уточнённое описание дефекта...
+    Description         *string `json:"description"`
+    // example: medium
+    Priority            *string `json:"priority"`               // low, medium, high
+    // example: 3
+    ResponsiblePersonID *uint   `json:"responsible_person_id"`  // 0 снимает ответственного
+    // example: 2025-12-31 23:59:59
+    Deadline            *string `json:"deadline"`               // format: "2006-01-02 15:04:05", "" убирает дедлайн
+    // example: in_progress
+    Status              *string `json:"status"`                 // подчиняется тем же правилам, что и UpdateStatus
+    // example: Переназначено на другую бригаду
+    Comment             string  `json:"comment"`                // комментарий к смене статуса
+}
+
+var defectPriorities = map[string]struct{}{
+	"low":    {},
+	"medium": {},
+	"high":   {},
+}
+
+// TransitionResponse описывает переход статуса дефекта.
+// swagger:model TransitionResponse
+type TransitionResponse struct {
+    // example: send_to_review
+    Name           string `json:"name"`
+    // example: in_progress
+    From           string `json:"from"`
+    // example: review
+    To             string `json:"to"`
+    // example: false
+    RequireComment bool   `json:"require_comment"`
+}
+
+// DefectHistoryResponse описывает одно изменение поля дефекта.
+// swagger:model DefectHistoryResponse
+type DefectHistoryResponse struct {
+    // example: 15
+    ID        uint       `json:"id"`
+    // example: 3
+    DefectID  uint       `json:"defect_id"`
+    // example: status
+    Field     string     `json:"field"`
+    // example: in_progress
+    OldValue  string     `json:"old_value"`
+    // example: review
+    NewValue  string     `json:"new_value"`
+    ChangedBy SimpleUser `json:"changed_by"`
+    // example: 2025-10-11T14:00:00Z
+    ChangedAt time.Time  `json:"changed_at"`
+}
+
+func toDefectHistoryResponse(h models.DefectHistory) DefectHistoryResponse {
+	return DefectHistoryResponse{
+		ID:        h.ID,
+		DefectID:  h.DefectID,
+		Field:     h.Field,
+		OldValue:  h.OldValue,
+		NewValue:  h.NewValue,
+		ChangedBy: toSimpleUser(h.ChangedBy),
+		ChangedAt: h.ChangedAt,
+	}
+}
+
+func toTransitionResponse(t workflow.Transition) TransitionResponse {
+	return TransitionResponse{Name: t.Name, From: t.From, To: t.To, RequireComment: t.RequireComment}
+}
+
+// changeStatus переводит дефект в статус to по схеме workflow и сохраняет приложенный комментарий.
+// Вызывается внутри транзакции, defect должен быть уже загружен; сам дефект не сохраняет.
+func (h *DefectHandler) changeStatus(tx *gorm.DB, defect *models.Defect, to, role, comment string, uid uint) error {
+	if defect.Status == to {
+		return fiber.NewError(fiber.StatusConflict, "defect already has status "+to)
+	}
+
+	if _, err := h.workflow.Check(defect.Status, to, role, comment); err != nil {
+		switch {
+		case errors.Is(err, workflow.ErrTransitionNotFound):
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("cannot change status from %s to %s", defect.Status, to))
+		case errors.Is(err, workflow.ErrTransitionForbidden):
+			return fiber.NewError(fiber.StatusForbidden, "role cannot perform this transition")
+		case errors.Is(err, workflow.ErrCommentRequired):
+			return fiber.NewError(fiber.StatusBadRequest, "comment is required for this transition")
+		}
+		return err
+	}
+
+	if err := history.EnterStatus(tx, defect.ID, to, uid, time.Now()); err != nil {
+		return err
+	}
+
+	if comment != "" {
+		if err := createComment(tx, &models.Comment{
+			DefectID:          defect.ID,
+			Text:              comment,
+			CreatedAt:         time.Now(),
+			CreatedByPersonID: uid,
+		}); err != nil {
+			return err
+		}
+	}
+
+	defect.Status = to
+	return nil
 }
 
 func toSimpleUser(u models.User) SimpleUser {
@@ -96,7 +232,47 @@ func toSimpleBuilding(b models.Building) SimpleBuilding {
 	return SimpleBuilding{ID: b.ID, Name: b.Name, Address: b.Address, Stage: b.Stage}
 }
 
-func toDefectResponse(d models.Defect) DefectResponse {
+// preloadDefect подгружает связи, нужные для DefectResponse.
+func preloadDefect(db *gorm.DB) *gorm.DB {
+	// здание и пользователи могли уйти в корзину, дефект всё равно показываем с ними
+	unscoped := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+	return db.Preload("Building", unscoped).Preload("CreatedBy", unscoped).Preload("Responsible", unscoped).
+		Preload("StatusPeriods", func(db *gorm.DB) *gorm.DB {
+			return db.Order("entered_at asc, id asc")
+		})
+}
+
+// toDefectMetrics считает сроки по интервалам статусов (periods отсортированы по entered_at).
+// Первая реакция — выход из начального статуса схемы wf, закрытие — вход в её статус closed.
+// Для дефектов без интервалов (созданных до их учёта) возвращает nil.
+func toDefectMetrics(d models.Defect, wf *workflow.Workflow, now time.Time) *DefectMetrics {
+	if len(d.StatusPeriods) == 0 {
+		return nil
+	}
+
+	m := &DefectMetrics{TimeInStatusHours: make(map[string]float64)}
+	for _, p := range d.StatusPeriods {
+		end := now
+		if p.LeftAt != nil {
+			end = *p.LeftAt
+		}
+		m.TimeInStatusHours[p.Status] += end.Sub(p.EnteredAt).Hours()
+
+		if m.FirstResponseHours == nil && p.Status != wf.Initial() {
+			h := p.EnteredAt.Sub(d.CreatedAt).Hours()
+			m.FirstResponseHours = &h
+		}
+		if d.Status == wf.Closed() && p.Status == wf.Closed() {
+			h := p.EnteredAt.Sub(d.CreatedAt).Hours()
+			m.CloseHours = &h
+		}
+	}
+	return m
+}
+
+func toDefectResponse(d models.Defect, wf *workflow.Workflow) DefectResponse {
 	var resp DefectResponse
 	resp.ID = d.ID
 	resp.BuildingID = d.BuildingID
@@ -121,12 +297,13 @@ func toDefectResponse(d models.Defect) DefectResponse {
 		tmp := d.Deadline
 		resp.Deadline = &tmp
 	}
+	resp.Metrics = toDefectMetrics(d, wf, time.Now())
 	return resp
 }
 
 // CreateDefect creates a new defect.
 // @Summary     Create defect
-// @Description Create a defect. Requires authentication.
+// @Description Create a defect. Requires authentication. The defect starts in the initial status of the workflow (default "new").
 // @Tags        defects
 // @Accept      json
 // @Produce     json
@@ -149,6 +326,9 @@ func (h *DefectHandler) CreateDefect(c *fiber.Ctx) error {
 	if req.Title == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title is required"})
 	}
+	if _, ok := defectPriorities[req.Priority]; req.Priority != "" && !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown priority, use one of: low, medium, high"})
+	}
 
 	// get current user from context (set by JWT middleware)
 	uidRaw := c.Locals("user_id")
@@ -193,12 +373,6 @@ func (h *DefectHandler) CreateDefect(c *fiber.Ctx) error {
 			}
 		}
 
-		// default status
-		status := req.Status
-		if status == "" {
-			status = "new"
-		}
-
 		defect := models.Defect{
 			BuildingID:          req.BuildingID,
 			CreatedByPersonID:   createdByID,
@@ -208,7 +382,7 @@ func (h *DefectHandler) CreateDefect(c *fiber.Ctx) error {
 			Priority:            req.Priority,
 			ResponsiblePersonID: 0,
 			Deadline:            deadline,
-			Status:              status,
+			Status:              h.workflow.Initial(), // дальше статус меняется только переходами схемы
 		}
 		if req.ResponsiblePersonID != nil {
 			defect.ResponsiblePersonID = *req.ResponsiblePersonID
@@ -218,8 +392,16 @@ func (h *DefectHandler) CreateDefect(c *fiber.Ctx) error {
 			return err
 		}
 
+		// история: все заданные поля считаем изменёнными относительно пустого дефекта
+		if err := history.Save(tx, history.DefectChanges(models.Defect{}, defect, createdByID, defect.CreatedAt)); err != nil {
+			return err
+		}
+		if err := history.EnterStatus(tx, defect.ID, defect.Status, createdByID, defect.CreatedAt); err != nil {
+			return err
+		}
+
 		// preload relations to return full response
-		if err := tx.Preload("Building").Preload("CreatedBy").Preload("Responsible").First(&defect, defect.ID).Error; err != nil {
+		if err := preloadDefect(tx).First(&defect, defect.ID).Error; err != nil {
 			return err
 		}
 		// attach defect to context for outer scope
@@ -252,74 +434,239 @@ func (h *DefectHandler) CreateDefect(c *fiber.Ctx) error {
 		}
 	}
 
-	resp := toDefectResponse(createdDefect)
+	resp := toDefectResponse(createdDefect, h.workflow)
 	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
+// Параметры фильтрации списка дефектов. Значения status и priority можно перечислять через запятую.
+var defectFilterParams = []string{
+	"status", "priority", "building_id", "responsible_id", "created_by_id",
+	"created_from", "created_to", "deadline_from", "deadline_to", "overdue", "unassigned",
+}
+
+// Поля сортировки списка дефектов и соответствующие им выражения ORDER BY.
+// Приоритет сортируется по важности, а не по алфавиту.
+var defectSortFields = map[string]string{
+	"deadline":   "defects.deadline",
+	"priority":   "CASE defects.priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END",
+	"created_at": "defects.created_at",
+	"updated_at": "defects.updated_at",
+}
+
+var defectSortFieldNames = []string{"deadline", "priority", "created_at", "updated_at"}
+
+// applyDefectFilters применяет к запросу по дефектам фильтры из query-параметров
+// (см. defectFilterParams). Используется списком дефектов и аналитикой.
+// Колонки квалифицированы именем таблицы, чтобы фильтры работали и в запросах с JOIN.
+// Завершённые статусы для overdue берутся из схемы wf.
+func applyDefectFilters(c *fiber.Ctx, q *gorm.DB, wf *workflow.Workflow) (*gorm.DB, error) {
+	if s := c.Query("status"); s != "" {
+		q = q.Where("defects.status IN ?", splitList(s))
+	}
+	if p := c.Query("priority"); p != "" {
+		priorities := splitList(p)
+		for _, pr := range priorities {
+			if _, ok := defectPriorities[pr]; !ok {
+				return nil, fmt.Errorf("unknown priority %q, use one of: low, medium, high", pr)
+			}
+		}
+		q = q.Where("defects.priority IN ?", priorities)
+	}
+	if b := c.Query("building_id"); b != "" {
+		bid, err := strconv.ParseUint(b, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid building_id")
+		}
+		q = q.Where("defects.building_id = ?", uint(bid))
+	}
+	if r := c.Query("responsible_id"); r != "" {
+		rid, err := strconv.ParseUint(r, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid responsible_id")
+		}
+		q = q.Where("defects.responsible_person_id = ?", uint(rid))
+	}
+	if cb := c.Query("created_by_id"); cb != "" {
+		cid, err := strconv.ParseUint(cb, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid created_by_id")
+		}
+		q = q.Where("defects.created_by_person_id = ?", uint(cid))
+	}
+
+	var err error
+	if q, err = applyDateRange(c, q, "defects.created_at", "created_from", "created_to"); err != nil {
+		return nil, err
+	}
+	if q, err = applyDateRange(c, q, "defects.deadline", "deadline_from", "deadline_to"); err != nil {
+		return nil, err
+	}
+
+	if v := c.Query("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("invalid overdue, use true or false")
+		}
+		if overdue {
+			q = q.Where(overdueCondition, wf.Final(), time.Time{}, time.Now())
+		}
+	}
+	if v := c.Query("unassigned"); v != "" {
+		unassigned, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("invalid unassigned, use true or false")
+		}
+		if unassigned {
+			q = q.Where("defects.responsible_person_id = 0")
+		}
+	}
+	return q, nil
+}
+
+// applyDateRange фильтрует column по параметрам fromParam / toParam.
+// Дата без времени в toParam включает весь день.
+func applyDateRange(c *fiber.Ctx, q *gorm.DB, column, fromParam, toParam string) (*gorm.DB, error) {
+	if v := c.Query(fromParam); v != "" {
+		from, _, err := parseDateParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, use '2006-01-02' or '2006-01-02 15:04:05'", fromParam)
+		}
+		q = q.Where(column+" >= ?", from)
+	}
+	if v := c.Query(toParam); v != "" {
+		to, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, use '2006-01-02' or '2006-01-02 15:04:05'", toParam)
+		}
+		if dateOnly {
+			q = q.Where(column+" < ?", to.AddDate(0, 0, 1))
+		} else {
+			q = q.Where(column+" <= ?", to)
+		}
+	}
+	return q, nil
+}
+
+// applyDefectSort применяет сортировку из параметра sort: поля через запятую,
+// "-" перед полем — по убыванию (например, sort=-priority,deadline).
+// id добавляется последним для стабильного порядка. sorted = false, если sort не задан.
+func applyDefectSort(c *fiber.Ctx, q *gorm.DB) (*gorm.DB, bool, error) {
+	s := c.Query("sort")
+	if s == "" {
+		return q, false, nil
+	}
+	for _, field := range splitList(s) {
+		desc := strings.HasPrefix(field, "-")
+		name := strings.TrimPrefix(field, "-")
+		expr, ok := defectSortFields[name]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown sort field %q, use one of: %s (prefix with - for descending)",
+				name, strings.Join(defectSortFieldNames, ", "))
+		}
+		if name == "deadline" {
+			// дефекты без дедлайна (нулевое время 0001-01-01) всегда в конце
+			q = q.Order("CASE WHEN defects.deadline > '0001-01-02' THEN 0 ELSE 1 END")
+		}
+		if desc {
+			expr += " DESC"
+		}
+		q = q.Order(expr)
+	}
+	return q.Order("defects.id"), true, nil
+}
+
+// checkQueryParams возвращает ошибку, если в запросе есть параметры не из allowed.
+func checkQueryParams(c *fiber.Ctx, allowed ...string) error {
+	for key := range c.Queries() {
+		found := false
+		for _, a := range allowed {
+			if key == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown query param %q, valid params: %s", key, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// splitList разбирает значение вида "a,b,c", пропуская пустые элементы.
+func splitList(v string) []string {
+	parts := strings.Split(v, ",")
+	res := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
 // GetDefects returns list of defects with optional filters.
 // @Summary     List defects
-// @Description Retrieve defects with optional filters and pagination
+// @Description Retrieve defects with optional filters, sorting and pagination (offset or keyset cursor on created_at, id). Unknown query params and sort fields are rejected with 400.
 // @Tags        defects
 // @Accept      json
 // @Produce     json
-// @Param       status         query     string  false  "Filter by status"
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
 // @Param       building_id    query     int     false  "Filter by building id"
 // @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Param       sort           query     string  false  "Comma-separated sort fields: deadline, priority, created_at, updated_at; prefix with - for descending"
 // @Param       limit          query     int     false  "Limit number of results (default 100)"
 // @Param       offset         query     int     false  "Offset for pagination (default 0)"
-// @Success     200  {array}   DefectResponse
+// @Param       cursor         query     string  false  "next_cursor from the previous page (not combinable with offset or sort)"
+// @Success     200  {object}  ListResponse[DefectResponse]
 // @Failure     400  {object}  common.ErrorResponse  "invalid query param"
 // @Failure     500  {object}  common.ErrorResponse
 // @Router      /api/defects [get]
 func (h *DefectHandler) GetDefects(c *fiber.Ctx) error {
-	q := h.db.Preload("Building").Preload("CreatedBy").Preload("Responsible")
-
-	// optional filters: status, building id, responsible person id
-	if s := c.Query("status"); s != "" {
-		q = q.Where("status = ?", s)
+	if err := checkQueryParams(c, append(defectFilterParams, "sort", "limit", "offset", "cursor")...); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if b := c.Query("building_id"); b != "" {
-		if bid, err := strconv.ParseUint(b, 10, 64); err == nil {
-			q = q.Where("building_id = ?", uint(bid))
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid building_id"})
-		}
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if r := c.Query("responsible_id"); r != "" {
-		if rid, err := strconv.ParseUint(r, 10, 64); err == nil {
-			q = q.Where("responsible_person_id = ?", uint(rid))
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid responsible_id"})
-		}
+	filtered, err := applyDefectFilters(c, h.db.Model(&models.Defect{}), h.workflow)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// pagination
-	limit := 100
-	if l := c.Query("limit"); l != "" {
-		if li, err := strconv.Atoi(l); err == nil && li > 0 {
-			limit = li
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid limit"})
-		}
+	q, sorted, err := applyDefectSort(c, preloadDefect(filtered.Session(&gorm.Session{})))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	offset := 0
-	if o := c.Query("offset"); o != "" {
-		if oi, err := strconv.Atoi(o); err == nil && oi >= 0 {
-			offset = oi
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid offset"})
-		}
+	// курсор задаёт позицию по (created_at, id), поэтому работает только с порядком по умолчанию
+	if sorted && page.After != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cursor cannot be combined with sort, use offset"})
+	}
+	if !sorted {
+		q = keysetOrder(q, "defects", false, page)
 	}
-	q = q.Limit(limit).Offset(offset)
 
 	var defects []models.Defect
-	if err := q.Find(&defects).Error; err != nil {
+	total, hasMore, err := findPage(filtered.Session(&gorm.Session{}), q, page, &defects)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	resp := make([]DefectResponse, 0, len(defects))
+	resp := ListResponse[DefectResponse]{Items: make([]DefectResponse, 0, len(defects)), Total: total}
 	for _, d := range defects {
-		resp = append(resp, toDefectResponse(d))
+		resp.Items = append(resp.Items, toDefectResponse(d, h.workflow))
+	}
+	if !sorted && hasMore {
+		last := defects[len(defects)-1]
+		resp.NextCursor = nextCursor(true, last.CreatedAt, last.ID)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -344,7 +691,7 @@ func (h *DefectHandler) GetDefect(c *fiber.Ctx) error {
 	}
 
 	var defect models.Defect
-	result := h.db.Preload("Building").Preload("CreatedBy").Preload("Responsible").First(&defect, id)
+	result := preloadDefect(h.db).First(&defect, id)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
 	}
@@ -352,25 +699,159 @@ func (h *DefectHandler) GetDefect(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect))
+	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect, h.workflow))
 }
 
-// UpdateStatus changes defect status according to role permissions.
+// UpdateDefect partially updates defect fields.
+// @Summary     Update defect
+// @Description Partially update a defect. Any subset of the create fields may be sent. Referenced building and responsible user must exist, priority must be one of low/medium/high. Status changes follow the same workflow rules as the status endpoint.
+// @Tags        defects
+// @Accept      json
+// @Produce     json
+// @Param       id      path      int                  true  "Defect ID"
+// @Param       payload body      UpdateDefectRequest  true  "Fields to update"
+// @Success     200     {object}  DefectResponse
+// @Failure     400     {object}  common.ErrorResponse  "invalid id, request body or field value"
+// @Failure     401     {object}  common.ErrorResponse  "unauthenticated"
+// @Failure     403     {object}  common.ErrorResponse  "role cannot perform this transition"
+// @Failure     404     {object}  common.ErrorResponse  "defect not found"
+// @Failure     409     {object}  common.ErrorResponse  "status transition not allowed from current status"
+// @Failure     500     {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id} [patch]
+func (h *DefectHandler) UpdateDefect(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var req UpdateDefectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.BuildingID == nil && req.Title == nil && req.Description == nil && req.Priority == nil &&
+		req.ResponsiblePersonID == nil && req.Deadline == nil && req.Status == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no fields to update"})
+	}
+
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+	role, _ := c.Locals("role").(string)
+
+	// validate fields that don't need the database
+	if req.Title != nil && *req.Title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title cannot be empty"})
+	}
+	if req.Priority != nil {
+		if _, ok := defectPriorities[*req.Priority]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown priority, use one of: low, medium, high"})
+		}
+	}
+	var deadline time.Time
+	if req.Deadline != nil && *req.Deadline != "" {
+		t, err := time.Parse(time.DateTime, *req.Deadline)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid deadline format, use '2000-01-02 00:00:00'"})
+		}
+		deadline = t
+	}
+	if req.Status != nil && *req.Status == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "status cannot be empty"})
+	}
+
+	var defect models.Defect
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&defect, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "defect not found")
+			}
+			return err
+		}
+		before := defect
+
+		if req.BuildingID != nil {
+			var building models.Building
+			if err := tx.First(&building, *req.BuildingID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fiber.NewError(fiber.StatusBadRequest, "building not found")
+				}
+				return err
+			}
+			defect.BuildingID = *req.BuildingID
+		}
+
+		// responsible_person_id = 0 снимает ответственного
+		if req.ResponsiblePersonID != nil {
+			if *req.ResponsiblePersonID != 0 {
+				var user models.User
+				if err := tx.First(&user, *req.ResponsiblePersonID).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return fiber.NewError(fiber.StatusBadRequest, "responsible person not found")
+					}
+					return err
+				}
+			}
+			defect.ResponsiblePersonID = *req.ResponsiblePersonID
+		}
+
+		if req.Title != nil {
+			defect.Title = *req.Title
+		}
+		if req.Description != nil {
+			defect.Description = *req.Description
+		}
+		if req.Priority != nil {
+			defect.Priority = *req.Priority
+		}
+		if req.Deadline != nil {
+			defect.Deadline = deadline
+		}
+		if req.Status != nil && *req.Status != defect.Status {
+			if err := h.changeStatus(tx, &defect, *req.Status, role, req.Comment, uid); err != nil {
+				return err
+			}
+		}
+		defect.UpdatedByPersonID = uid
+
+		// Omit associations: иначе Save попытается upsert-нуть пустые Building/CreatedBy/...
+		if err := tx.Omit(clause.Associations).Save(&defect).Error; err != nil {
+			return err
+		}
+		if err := history.Save(tx, history.DefectChanges(before, defect, uid, defect.UpdatedAt)); err != nil {
+			return err
+		}
+
+		return preloadDefect(tx).First(&defect, defect.ID).Error
+	})
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update defect"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect, h.workflow))
+}
+
+// UpdateStatus changes defect status according to the configured workflow.
 // @Summary     Update defect status
-// @Description Change status of a defect. Allowed status transitions depend on user role (engineer/manager). Allowed status values: new, in_progress, review, closed.
+// @Description Change status of a defect. Only transitions described in the workflow (from current status, for the user's role) are allowed. Some transitions require a comment, which is saved as a defect comment; @mentions in it work as in POST /api/comments (every @login must be an existing user).
 // @Tags        defects
 // @Accept      json
 // @Produce     json
 // @Param       id      path      int             true  "Defect ID"
 // @Param       payload body      UpdateStatusReq  true  "New status"
 // @Success     200     {object}  DefectResponse
-// @Failure     400     {object}  common.ErrorResponse  "invalid id or request body or missing status"
+// @Failure     400     {object}  common.ErrorResponse  "invalid id or request body, missing status or comment, unknown mentioned user"
 // @Failure     401     {object}  common.ErrorResponse  "unauthenticated"
-// @Failure     403     {object}  common.ErrorResponse  "insufficient permissions or role cannot set this status"
+// @Failure     403     {object}  common.ErrorResponse  "role cannot perform this transition"
 // @Failure     404     {object}  common.ErrorResponse  "defect not found"
+// @Failure     409     {object}  common.ErrorResponse  "status transition not allowed from current status"
 // @Failure     500     {object}  common.ErrorResponse
 // @Security    BearerAuth
-// @Router 		/api/defects/{id} [patch]
+// @Router 		/api/defects/{id}/status [patch]
 func (h *DefectHandler) UpdateStatus(c *fiber.Ctx) error {
 	// parse id
 	id, err := c.ParamsInt("id")
@@ -406,61 +887,88 @@ func (h *DefectHandler) UpdateStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invalid user id in context"})
 	}
 
-	// define allowed targets per role
-	allowed := map[string]struct{}{}
-	switch role {
-	case "engineer":
-		allowed["in_progress"] = struct{}{}
-		allowed["review"] = struct{}{}
-	case "manager":
-		allowed["in_progress"] = struct{}{}
-		allowed["review"] = struct{}{}
-		allowed["closed"] = struct{}{}
-	case "observer":
-		allowed["new"] = struct{}{}
-		allowed["in_progress"] = struct{}{}
-		allowed["review"] = struct{}{}
-		allowed["closed"] = struct{}{}
-	default:
-		// other roles can't change status
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "insufficient permissions"})
-	}
-
-	// check requested status is allowed
-	if _, ok := allowed[req.Status]; !ok {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "role cannot set this status"})
-	}
-
-	// load defect
 	var defect models.Defect
-	res := h.db.First(&defect, id)
-	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&defect, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "defect not found")
+			}
+			return err
+		}
+		before := defect
+
+		if err := h.changeStatus(tx, &defect, req.Status, role, req.Comment, uid); err != nil {
+			return err
+		}
+		defect.UpdatedByPersonID = uid
+
+		if err := tx.Omit(clause.Associations).Save(&defect).Error; err != nil {
+			return err
+		}
+		if err := history.Save(tx, history.DefectChanges(before, defect, uid, defect.UpdatedAt)); err != nil {
+			return err
+		}
+
+		// reload with relations for response
+		return preloadDefect(tx).First(&defect, defect.ID).Error
+	})
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save status"})
 	}
-	if res.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+
+	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect, h.workflow))
+}
+
+// GetTransitions returns status transitions available to the current user.
+// @Summary     List available status transitions
+// @Description Returns workflow transitions that the current user can apply to the defect from its current status.
+// @Tags        defects
+// @Accept      json
+// @Produce     json
+// @Param       id   path      int  true  "Defect ID"
+// @Success     200  {array}   TransitionResponse
+// @Failure     400  {object}  common.ErrorResponse  "invalid id"
+// @Failure     401  {object}  common.ErrorResponse  "unauthenticated"
+// @Failure     404  {object}  common.ErrorResponse  "defect not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id}/transitions [get]
+func (h *DefectHandler) GetTransitions(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
 	}
 
-	// update fields
-	defect.Status = req.Status
-	defect.UpdatedByPersonID = uid
+	role, ok := c.Locals("role").(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
 
-	if err := h.db.Save(&defect).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save status"})
+	var defect models.Defect
+	result := h.db.First(&defect, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
+	}
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
 
-	// reload with relations for response
-	if err := h.db.Preload("Building").Preload("CreatedBy").Preload("Responsible").First(&defect, defect.ID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load defect"})
+	transitions := h.workflow.Available(defect.Status, role)
+	resp := make([]TransitionResponse, 0, len(transitions))
+	for _, t := range transitions {
+		resp = append(resp, toTransitionResponse(t))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect))
+	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
 
-// DeleteDefect deletes defect by id.
+// DeleteDefect moves defect to trash.
 // @Summary     Delete defect
-// @Description Delete defect by id
+// @Description Soft-delete defect by id. The defect is hidden from all queries and can be restored or purged from trash. The deletion is recorded in the defect history.
 // @Tags        defects
 // @Accept      json
 // @Produce     plain
@@ -470,6 +978,7 @@ func (h *DefectHandler) UpdateStatus(c *fiber.Ctx) error {
 // @Failure     404  {object}  common.ErrorResponse
 // @Failure     500  {object}  common.ErrorResponse
 // @Security    BearerAuth
+// @Failure     401  {object}  common.ErrorResponse
 // @Router      /api/defects/{id} [delete]
 func (h *DefectHandler) DeleteDefect(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
@@ -492,13 +1001,278 @@ func (h *DefectHandler) DeleteDefect(c *fiber.Ctx) error {
 		})
 	}
 
-	// Пока что оставлю удаление чисто дефекта. Связанные с ним сущности удалять надо вручную.
-	// TODO: обернуть в транзацкию для удаления связанных сущностей
-	if err := h.db.Delete(&defect).Error; err != nil {
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	// мягкое удаление: дефект уходит в корзину, связанные записи остаются для восстановления;
+	// кто удалил, записывается и в историю — deleted_by_person_id очищается при восстановлении
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := softDelete(tx, &defect, uid); err != nil {
+			return err
+		}
+		return history.Save(tx, history.Deleted(defect.ID, uid, time.Now()))
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to delete defect",
 		})
 	}
 
 	return c.Status(fiber.StatusOK).SendString("Successfully deleted defect with id " + strconv.Itoa(int(defect.ID)))
-}
\ No newline at end of file
+}
+
+// DeletedDefectResponse описывает дефект в корзине.
+// swagger:model DeletedDefectResponse
+type DeletedDefectResponse struct {
+	DefectResponse
+	DeletionInfo
+}
+
+// GetDeletedDefects returns defects in trash.
+// @Summary     List deleted defects
+// @Description Retrieve soft-deleted defects page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)
+// @Tags        defects
+// @Accept      json
+// @Produce     json
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object}  ListResponse[DeletedDefectResponse]
+// @Failure     400  {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/trash [get]
+func (h *DefectHandler) GetDeletedDefects(c *fiber.Ctx) error {
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var defects []models.Defect
+	q := trashOrder(preloadDefect(trashScope(h.db.Model(&models.Defect{}))), "defects", page)
+	total, hasMore, err := findPage(trashScope(h.db.Model(&models.Defect{})), q, page, &defects)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[DeletedDefectResponse]{Items: make([]DeletedDefectResponse, 0, len(defects)), Total: total}
+	for _, d := range defects {
+		resp.Items = append(resp.Items, DeletedDefectResponse{
+			DefectResponse: toDefectResponse(d, h.workflow),
+			DeletionInfo:   toDeletionInfo(d.DeletedAt, d.DeletedByPersonID),
+		})
+	}
+	if hasMore {
+		last := defects[len(defects)-1]
+		resp.NextCursor = nextCursor(true, last.DeletedAt.Time, last.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreDefect restores defect from trash.
+// @Summary     Restore defect
+// @Description Restore a soft-deleted defect. Both deletion and restore are recorded in the defect history as changes of the deleted_at field.
+// @Tags        defects
+// @Accept      json
+// @Produce     json
+// @Param       id   path      int  true  "Defect ID"
+// @Success     200  {object}  DefectResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted defect not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id}/restore [post]
+func (h *DefectHandler) RestoreDefect(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var defect models.Defect
+	if err := findDeleted(h.db, &defect, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted defect not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	deletedAt := defect.DeletedAt.Time
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := restoreDeleted(tx, &defect); err != nil {
+			return err
+		}
+		return history.Save(tx, history.Restored(defect.ID, deletedAt, uid, time.Now()))
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to restore defect"})
+	}
+
+	if err := preloadDefect(h.db).First(&defect, defect.ID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load defect"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toDefectResponse(defect, h.workflow))
+}
+
+// PurgeDefect permanently deletes a defect from trash.
+// @Summary     Purge defect
+// @Description Permanently delete a soft-deleted defect together with its comments, comment revisions, attachments and history in one transaction. Attachment files are removed after the transaction commits.
+// @Tags        defects
+// @Accept      json
+// @Produce     plain
+// @Param       id   path      int  true  "Defect ID"
+// @Success     200  {string}  string  "Permanently deleted defect with id {id}"
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted defect not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id}/purge [delete]
+func (h *DefectHandler) PurgeDefect(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var defect models.Defect
+	if err := findDeleted(h.db, &defect, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted defect not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	// удаляем дефект вместе со всеми зависимыми записями одной транзакцией,
+	// файлы вложений удаляем только после успешного коммита
+	var files releasedFiles
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		files, err = deleteDefectCascade(tx, defect.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to purge defect"})
+	}
+	removeReleased(c.UserContext(), h.db, h.store, files)
+
+	return c.Status(fiber.StatusOK).SendString("Permanently deleted defect with id " + strconv.Itoa(int(defect.ID)))
+}
+
+// deleteDefectCascade удаляет дефект и все зависимые записи (комментарии, их вложения, правки и упоминания,
+// вложения дефекта, историю, интервалы статусов). Должна вызываться внутри транзакции.
+// Возвращает файлы вложений, на которые больше никто не ссылается, — их нужно удалить после коммита (removeReleased).
+func deleteDefectCascade(tx *gorm.DB, defectID uint) (releasedFiles, error) {
+	// Unscoped: в корзине могут лежать и сами комментарии, и дефект
+	tx = tx.Unscoped().Session(&gorm.Session{})
+	commentIDs := tx.Model(&models.Comment{}).Select("id").Where("defect_id = ?", defectID)
+
+	var commentAttachments []models.CommentAttachment
+	if err := tx.Where("comment_id IN (?)", commentIDs).Find(&commentAttachments).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	var defectAttachments []models.DefectAttachment
+	if err := tx.Where("defect_id = ?", defectID).Find(&defectAttachments).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	refs := append(commentAttachmentFiles(commentAttachments), defectAttachmentFiles(defectAttachments)...)
+
+	if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&models.CommentAttachment{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&models.CommentRevision{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&models.CommentMention{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("defect_id = ?", defectID).Delete(&models.Comment{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("defect_id = ?", defectID).Delete(&models.DefectAttachment{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("defect_id = ?", defectID).Delete(&models.DefectHistory{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Where("defect_id = ?", defectID).Delete(&models.DefectStatusPeriod{}).Error; err != nil {
+		return releasedFiles{}, err
+	}
+	if err := tx.Delete(&models.Defect{}, defectID).Error; err != nil {
+		return releasedFiles{}, err
+	}
+
+	return releaseFiles(tx, refs)
+}
+
+// GetHistory returns the change history of a defect.
+// @Summary     Get defect history
+// @Description Returns per-field changes of the defect (old value, new value, who and when) page by page in chronological order (offset or keyset cursor on changed_at, id).
+// @Tags        defects
+// @Accept      json
+// @Produce     json
+// @Param       id      path      int     true   "Defect ID"
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200  {object}  ListResponse[DefectHistoryResponse]
+// @Failure     400  {object}  common.ErrorResponse  "invalid id or query param"
+// @Failure     401  {object}  common.ErrorResponse  "unauthenticated"
+// @Failure     404  {object}  common.ErrorResponse  "defect not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/defects/{id}/history [get]
+func (h *DefectHandler) GetHistory(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var defect models.Defect
+	result := h.db.First(&defect, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "defect not found"})
+	}
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var entries []models.DefectHistory
+	filtered := h.db.Model(&models.DefectHistory{}).Where("defect_id = ?", defect.ID)
+	// автор правки мог уйти в корзину, историю всё равно показываем целиком
+	q := keysetOrderBy(filtered.Session(&gorm.Session{}).Preload("ChangedBy", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}), "defect_histories", "changed_at", false, page)
+	total, hasMore, err := findPage(filtered.Session(&gorm.Session{}), q, page, &entries)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[DefectHistoryResponse]{Items: make([]DefectHistoryResponse, 0, len(entries)), Total: total}
+	for _, e := range entries {
+		resp.Items = append(resp.Items, toDefectHistoryResponse(e))
+	}
+	if hasMore {
+		last := entries[len(entries)-1]
+		resp.NextCursor = nextCursor(true, last.ChangedAt, last.ID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
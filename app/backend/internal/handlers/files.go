@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Каталог (префикс ключей) файлов вложений в хранилище. Файлы, загруженные до
+// дедупликации, лежат в defect_attachments/ и comment_attachments/.
+const storedFilesPrefix = "files"
+
+var (
+	errFileRequired = errors.New("file or hash required")
+	errInvalidHash  = errors.New("hash must be a hex-encoded SHA-256")
+	errHashNotFound = errors.New("file with this hash not found, upload the file itself")
+)
+
+// storedFile описывает файл вложения в хранилище.
+type storedFile struct {
+	Key         string
+	Hash        string
+	FileName    string
+	Size        int64
+	ContentType string
+	// файл записан в хранилище этой загрузкой (раньше такого содержимого не было)
+	New bool
+}
+
+// storeFormFile добавляет в транзакции tx ссылку на файл вложения из запроса: загруженный
+// файл (поле "file") или уже имеющийся на сервере файл по SHA-256 (поле "hash" и
+// необязательное "filename").
+func storeFormFile(c *fiber.Ctx, tx *gorm.DB, store storage.Storage, policy upload.Policy) (storedFile, error) {
+	if file, err := c.FormFile("file"); err == nil {
+		return storeUpload(c.UserContext(), tx, store, policy, file)
+	}
+	if hash := c.FormValue("hash"); hash != "" {
+		return reuseStored(tx, policy, hash, c.FormValue("filename"))
+	}
+	return storedFile{}, errFileRequired
+}
+
+// storeUpload проверяет загруженный файл по политике и добавляет на него ссылку в транзакции tx.
+// Файлы хранятся по SHA-256 содержимого ("files/<hash><расширение>"): если такой файл
+// уже есть, он не записывается повторно. Тип файла определяется по содержимому; имя
+// и Content-Type от клиента в ключ и в хранилище не попадают.
+// Ошибки проверки — upload.ErrUnsupportedType и *upload.TooLargeError (см. uploadError).
+func storeUpload(ctx context.Context, tx *gorm.DB, store storage.Storage, policy upload.Policy, file *multipart.FileHeader) (storedFile, error) {
+	src, err := file.Open()
+	if err != nil {
+		return storedFile{}, err
+	}
+	defer src.Close()
+
+	head := make([]byte, upload.SniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return storedFile{}, err
+	}
+	contentType := upload.DetectType(head[:n])
+	if err := policy.Check(contentType, file.Size); err != nil {
+		return storedFile{}, err
+	}
+
+	sum := sha256.New()
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return storedFile{}, err
+	}
+	if _, err := io.Copy(sum, src); err != nil {
+		return storedFile{}, err
+	}
+	hash := hex.EncodeToString(sum.Sum(nil))
+
+	// +1 ссылка; ref_count = 1 после upsert значит, что файла ещё не было или он ждёт
+	// удаления (removeReleased) — тогда файл записывается заново. Параллельная
+	// загрузка того же файла ждёт на блокировке строки, пока этот файл не будет записан.
+	sf := models.StoredFile{
+		Hash:        hash,
+		Key:         upload.Key(storedFilesPrefix, hash, contentType),
+		Size:        file.Size,
+		ContentType: contentType,
+		RefCount:    1,
+	}
+	err = tx.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.Assignments(map[string]any{"ref_count": gorm.Expr("stored_files.ref_count + 1")}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "key"}, {Name: "ref_count"}}},
+	).Create(&sf).Error
+	if err != nil {
+		return storedFile{}, err
+	}
+
+	if sf.RefCount == 1 {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return storedFile{}, err
+		}
+		if err := store.Put(ctx, sf.Key, src, file.Size, contentType); err != nil {
+			return storedFile{}, err
+		}
+	}
+	return storedFile{
+		Key:         sf.Key,
+		Hash:        hash,
+		FileName:    upload.SanitizeFilename(file.Filename),
+		Size:        file.Size,
+		ContentType: contentType,
+		New:         sf.RefCount == 1,
+	}, nil
+}
+
+// reuseStored добавляет в транзакции tx ссылку на уже загруженный файл с хэшем hash —
+// так клиент может не загружать файл, который уже есть на сервере. Тип файла снова
+// проверяется по политике: она могла измениться с момента загрузки.
+func reuseStored(tx *gorm.DB, policy upload.Policy, hash, filename string) (storedFile, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if !upload.ValidHash(hash) {
+		return storedFile{}, errInvalidHash
+	}
+
+	// запись с ref_count = 0 ждёт удаления файла (removeReleased) — такой файл уже не выдаём
+	res := tx.Model(&models.StoredFile{}).Where("hash = ? AND ref_count > 0", hash).Update("ref_count", gorm.Expr("ref_count + 1"))
+	if res.Error != nil {
+		return storedFile{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return storedFile{}, errHashNotFound
+	}
+	var sf models.StoredFile
+	if err := tx.First(&sf, "hash = ?", hash).Error; err != nil {
+		return storedFile{}, err
+	}
+	if err := policy.Check(sf.ContentType, sf.Size); err != nil {
+		return storedFile{}, err
+	}
+
+	if filename == "" {
+		filename = path.Base(sf.Key)
+	}
+	return storedFile{
+		Key:         sf.Key,
+		Hash:        sf.Hash,
+		FileName:    upload.SanitizeFilename(filename),
+		Size:        sf.Size,
+		ContentType: sf.ContentType,
+	}, nil
+}
+
+// attachmentFile ссылка вложения на файл в хранилище.
+type attachmentFile struct {
+	Key  string
+	Hash string
+}
+
+// releasedFiles файлы, ссылки на которые сняты транзакцией; удаляются removeReleased
+// после её коммита.
+type releasedFiles struct {
+	// файлы вложений без хэша (загруженные до дедупликации) вместе с копиями
+	keys []string
+	// хэши stored_files, у которых не осталось ссылок, и ключи их файлов
+	stored map[string]string
+}
+
+// releaseFiles снимает в транзакции tx ссылки удаляемых вложений на файлы.
+// Запись stored_files без ссылок не удаляется здесь, а остаётся с ref_count = 0: её строку
+// removeReleased блокирует после коммита, чтобы файл не удалился из-под повторной загрузки
+// того же содержимого. Файлы вложений без хэша принадлежат только своему вложению.
+func releaseFiles(tx *gorm.DB, refs []attachmentFile) (releasedFiles, error) {
+	released := releasedFiles{stored: map[string]string{}}
+	counts := map[string]int{}
+	keys := map[string]string{}
+	for _, r := range refs {
+		if r.Hash == "" {
+			released.keys = append(released.keys, storedFileKeys(r.Key)...)
+			continue
+		}
+		counts[r.Hash]++
+		keys[r.Hash] = r.Key
+	}
+
+	// в одном порядке, чтобы параллельные удаления не блокировали друг друга крест-накрест
+	hashes := make([]string, 0, len(counts))
+	for h := range counts {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	for _, h := range hashes {
+		var sf models.StoredFile
+		res := tx.Model(&sf).Clauses(clause.Returning{Columns: []clause.Column{{Name: "ref_count"}}}).
+			Where("hash = ?", h).
+			Update("ref_count", gorm.Expr("ref_count - ?", counts[h]))
+		if res.Error != nil {
+			return releasedFiles{}, res.Error
+		}
+		if res.RowsAffected > 0 && sf.RefCount <= 0 {
+			released.stored[h] = keys[h]
+		}
+	}
+	return released, nil
+}
+
+// removeReleased удаляет из хранилища файлы, освобождённые releaseFiles. Общий файл
+// удаляется под блокировкой строки stored_files и только если ссылок на него так и не
+// появилось; ошибки только логируются — остаток подберёт сверка хранилища (cmd/cleanup).
+func removeReleased(ctx context.Context, db *gorm.DB, store storage.Storage, files releasedFiles) {
+	removeStoredFiles(ctx, store, files.keys...)
+	for hash, key := range files.stored {
+		if err := dropStoredFile(ctx, db, store, hash, key); err != nil {
+			log.Warn().Err(err).Str("hash", hash).Msg("failed to remove stored file")
+		}
+	}
+}
+
+// dropStoredFile удаляет файл с хэшем hash и запись о нём, если на него нет ссылок.
+// Если записи нет (загрузка откатилась), вставляется заглушка с ref_count = 0: параллельная
+// загрузка того же файла ждёт на ней коммита и затем записывает файл заново.
+func dropStoredFile(ctx context.Context, db *gorm.DB, store storage.Storage, hash, key string) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		placeholder := models.StoredFile{Hash: hash, Key: key}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&placeholder).Error; err != nil {
+			return err
+		}
+		var sf models.StoredFile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sf, "hash = ?", hash).Error; err != nil {
+			return err
+		}
+		if sf.RefCount > 0 {
+			return nil
+		}
+		for _, k := range storedFileKeys(sf.Key) {
+			if err := store.Delete(ctx, k); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&sf).Error
+	})
+}
+
+// storedFileKeys ключ файла и ключи его уменьшенных копий (для не-изображений их нет,
+// удаление несуществующего ключа ничего не делает).
+func storedFileKeys(key string) []string {
+	return []string{key, preview.Key(key, preview.Thumbnail), preview.Key(key, preview.Medium)}
+}
+
+// uploadError отвечает на ошибку storeFormFile: 413 и 415 для непрошедших проверку файлов,
+// 400 для запроса без файла или с неверным хэшем, 404 для неизвестного хэша, 500 для остального.
+func uploadError(c *fiber.Ctx, err error) error {
+	var tooLarge *upload.TooLargeError
+	switch {
+	case errors.Is(err, errFileRequired), errors.Is(err, errInvalidHash):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errHashNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.As(err, &tooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": tooLarge.Error()})
+	case errors.Is(err, upload.ErrUnsupportedType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save file"})
+	}
+}
+
+// sendStored отдаёт объект из хранилища потоком. Если filename не пуст, файл отдаётся
+// как вложение (Content-Disposition: attachment) с этим именем.
+func sendStored(c *fiber.Ctx, store storage.Storage, key, filename string) error {
+	obj, err := getStored(c, store, key)
+	if obj == nil {
+		return err
+	}
+	setStoredHeaders(c, obj, filename)
+	// тело закрывается после отправки
+	return c.SendStream(obj.Body, int(obj.Size))
+}
+
+// sendStoredWithoutExif отдаёт фото из хранилища без метаданных EXIF (координаты,
+// устройство, время съёмки); остальные файлы — как sendStored. Оригинал в хранилище не меняется.
+func sendStoredWithoutExif(c *fiber.Ctx, store storage.Storage, key, filename string) error {
+	obj, err := getStored(c, store, key)
+	if obj == nil {
+		return err
+	}
+	setStoredHeaders(c, obj, filename)
+
+	switch obj.ContentType {
+	case "image/jpeg":
+		// размер после удаления сегментов заранее неизвестен — отдаём без Content-Length
+		pr, pw := io.Pipe()
+		go func() {
+			err := exif.StripJPEG(pw, obj.Body)
+			obj.Body.Close()
+			pw.CloseWithError(err)
+		}()
+		return c.SendStream(pr)
+	case "image/heic", "image/heif": // локальный драйвер определяет тип по расширению .heic
+		defer obj.Body.Close()
+		data, err := io.ReadAll(obj.Body)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read file"})
+		}
+		if err := exif.StripHEIC(data); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("failed to strip exif from heic")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read file"})
+		}
+		return c.Send(data)
+	default:
+		return c.SendStream(obj.Body, int(obj.Size))
+	}
+}
+
+// copyWithoutExif копирует фото из r в w без метаданных EXIF (см. sendStoredWithoutExif),
+// остальные файлы — как есть.
+func copyWithoutExif(w io.Writer, r io.Reader, contentType string) error {
+	switch contentType {
+	case "image/jpeg":
+		return exif.StripJPEG(w, r)
+	case "image/heic", "image/heif":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if err := exif.StripHEIC(data); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		_, err := io.Copy(w, r)
+		return err
+	}
+}
+
+// getStored открывает объект хранилища. Если объекта нет или хранилище недоступно,
+// отправляет 404 или 500 и возвращает nil-объект с результатом отправки.
+func getStored(c *fiber.Ctx, store storage.Storage, key string) (*storage.Object, error) {
+	obj, err := store.Get(c.UserContext(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "file not found"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	return obj, nil
+}
+
+func setStoredHeaders(c *fiber.Ctx, obj *storage.Object, filename string) {
+	if filename != "" {
+		// выставляет Content-Disposition и Content-Type по расширению имени
+		c.Attachment(filename)
+	}
+	if obj.ContentType != "" {
+		c.Set(fiber.HeaderContentType, obj.ContentType)
+	} else if filename == "" {
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	}
+	if !obj.ModTime.IsZero() {
+		c.Set(fiber.HeaderLastModified, obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+}
+
+// removeStoredFiles удаляет файлы вложений из хранилища. Ошибки только логируются:
+// записи в БД к этому моменту уже удалены, откатывать нечего.
+func removeStoredFiles(ctx context.Context, store storage.Storage, keys ...string) {
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil {
+			log.Warn().Err(err).Str("key", k).Msg("failed to remove attachment file")
+		}
+	}
+}
+
+// attachmentFilename имя файла вложения для ответа и скачивания. У вложений, загруженных
+// до появления поля filename, имя восстанавливается из ключа "<prefix>/<unixnano>_<имя>".
+func attachmentFilename(fileName, key string) string {
+	if fileName != "" {
+		return fileName
+	}
+	return originalFilename(key)
+}
+
+// originalFilename убирает из ключа сохранённого файла каталог и префикс "<unixnano>_".
+func originalFilename(key string) string {
+	name := path.Base(key)
+	if _, rest, ok := strings.Cut(name, "_"); ok && rest != "" {
+		return rest
+	}
+	return name
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// mentionPattern находит @login в тексте. Перед @ не должно быть буквы или цифры,
+// чтобы адреса почты (ivan@example.com) не считались упоминаниями. Логины при регистрации
+// не ограничены ASCII, поэтому буквы и цифры — любые Unicode (\w в RE2 — только ASCII).
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_.\-]+)`)
+
+// parseMentions возвращает логины, упомянутые в тексте, без повторов в порядке появления.
+func parseMentions(text string) []string {
+	var logins []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// точка или дефис в конце — это пунктуация: "спасибо, @ivan."
+		login := strings.TrimRight(m[1], ".-")
+		if login == "" || seen[login] {
+			continue
+		}
+		seen[login] = true
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+// resolveMentions находит пользователей, упомянутых в тексте. Если какого-то логина нет
+// среди пользователей, возвращает ошибку со списком неизвестных логинов.
+func resolveMentions(db *gorm.DB, text string) ([]models.User, error) {
+	logins := parseMentions(text)
+	if len(logins) == 0 {
+		return nil, nil
+	}
+
+	var users []models.User
+	if err := db.Where("login IN ?", logins).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(users))
+	for _, u := range users {
+		found[u.Login] = true
+	}
+	var unknown []string
+	for _, login := range logins {
+		if !found[login] {
+			unknown = append(unknown, "@"+login)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, &unknownMentionsError{logins: unknown}
+	}
+	return users, nil
+}
+
+type unknownMentionsError struct {
+	logins []string
+}
+
+func (e *unknownMentionsError) Error() string {
+	return fmt.Sprintf("unknown users mentioned: %s", strings.Join(e.logins, ", "))
+}
+
+// syncMentions заменяет упоминания комментария на users. Должна вызываться внутри транзакции.
+func syncMentions(tx *gorm.DB, commentID uint, users []models.User) error {
+	if err := tx.Where("comment_id = ?", commentID).Delete(&models.CommentMention{}).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	mentions := make([]models.CommentMention, 0, len(users))
+	for _, u := range users {
+		mentions = append(mentions, models.CommentMention{CommentID: commentID, UserID: u.ID})
+	}
+	return tx.Create(&mentions).Error
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // логины через запятую
+	}{
+		{"латиница", "@ivan посмотри", "ivan"},
+		{"кириллица", "@иван посмотри", "иван"},
+		{"кириллица с цифрами и точкой", "передаю @пётр.сидоров2", "пётр.сидоров2"},
+		{"несколько без повторов", "@ivan и @иван, ещё раз @ivan", "ivan,иван"},
+		{"пунктуация в конце", "спасибо, @иван.", "иван"},
+		{"после скобки", "(@ivan)", "ivan"},
+		{"почта", "пишите на ivan@example.com", ""},
+		{"почта с кириллицей", "иван@почта.рф", ""},
+		{"двойная @", "@@ivan", ""},
+		{"одна @", "email @ домен", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(parseMentions(tt.text), ","); got != tt.want {
+				t.Errorf("parseMentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Общий формат списковых эндпоинтов. Поддерживаются два режима:
+//   - offset: limit + offset, как раньше;
+//   - cursor: limit + cursor из next_cursor предыдущей страницы. Курсор — позиция
+//     последней записи по ключу (created_at, id), поэтому новые записи, появившиеся
+//     во время листания, не сдвигают страницы. Корзина и история листаются по своему
+//     времени (deleted_at, changed_at) — в курсоре тогда лежит оно.
+
+const defaultPageLimit = 100
+
+// ListResponse единый ответ списковых эндпоинтов.
+// next_cursor = null, если следующей страницы нет.
+type ListResponse[T any] struct {
+	Items []T `json:"items"`
+	// всего записей с учётом фильтров
+	// example: 240
+	Total int64 `json:"total"`
+	// example: eyJ0IjoiMjAyNS0xMC0xMVQxNDowMDowMFoiLCJpZCI6NDJ9
+	NextCursor *string `json:"next_cursor"`
+}
+
+// pageCursor позиция последней записи страницы.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// pageParams параметры пагинации из query (limit, offset, cursor).
+type pageParams struct {
+	Limit  int
+	Offset int
+	After  *pageCursor
+}
+
+func parsePageParams(c *fiber.Ctx) (pageParams, error) {
+	p := pageParams{Limit: defaultPageLimit}
+	if l := c.Query("limit"); l != "" {
+		li, err := strconv.Atoi(l)
+		if err != nil || li <= 0 {
+			return p, errors.New("invalid limit")
+		}
+		p.Limit = li
+	}
+	if o := c.Query("offset"); o != "" {
+		oi, err := strconv.Atoi(o)
+		if err != nil || oi < 0 {
+			return p, errors.New("invalid offset")
+		}
+		p.Offset = oi
+	}
+	if cur := c.Query("cursor"); cur != "" {
+		if p.Offset != 0 {
+			return p, errors.New("use either offset or cursor, not both")
+		}
+		after, err := decodeCursor(cur)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		p.After = after
+	}
+	return p, nil
+}
+
+func encodeCursor(createdAt time.Time, id uint) string {
+	b, _ := json.Marshal(pageCursor{CreatedAt: createdAt, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, err
+	}
+	if cur.ID == 0 {
+		return nil, errors.New("empty cursor")
+	}
+	return &cur, nil
+}
+
+// keysetOrder упорядочивает q по (created_at, id) таблицы table и применяет курсор p.
+// desc — новые записи первыми.
+func keysetOrder(q *gorm.DB, table string, desc bool, p pageParams) *gorm.DB {
+	return keysetOrderBy(q, table, "created_at", desc, p)
+}
+
+// keysetOrderBy то же, что keysetOrder, но по (column, id): column — колонка времени
+// таблицы table (только доверенные имена!).
+func keysetOrderBy(q *gorm.DB, table, column string, desc bool, p pageParams) *gorm.DB {
+	op, dir := ">", ""
+	if desc {
+		op, dir = "<", " DESC"
+	}
+	col := table + "." + column
+	if p.After != nil {
+		q = q.Where("("+col+" "+op+" ? OR ("+col+" = ? AND "+table+".id "+op+" ?))",
+			p.After.CreatedAt, p.After.CreatedAt, p.After.ID)
+	}
+	return q.Order(col + dir).Order(table + ".id" + dir)
+}
+
+// findPage считает total по countQuery и загружает страницу q в dest.
+// countQuery не должен содержать ORDER BY и курсор: total — число всех записей по фильтрам.
+// Возвращает true, если за страницей есть ещё записи.
+func findPage[T any](countQuery, q *gorm.DB, p pageParams, dest *[]T) (total int64, hasMore bool, err error) {
+	if err := countQuery.Count(&total).Error; err != nil {
+		return 0, false, err
+	}
+	// одна лишняя запись показывает, есть ли следующая страница
+	if err := q.Limit(p.Limit + 1).Offset(p.Offset).Find(dest).Error; err != nil {
+		return 0, false, err
+	}
+	if len(*dest) > p.Limit {
+		*dest = (*dest)[:p.Limit]
+		hasMore = true
+	}
+	return total, hasMore, nil
+}
+
+// nextCursor возвращает курсор следующей страницы по последней записи или nil, если страница последняя.
+func nextCursor(hasMore bool, createdAt time.Time, id uint) *string {
+	if !hasMore {
+		return nil
+	}
+	cur := encodeCursor(createdAt, id)
+	return &cur
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"html"
+	"strings"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"github.com/Quasar777/buildefect/app/backend/internal/common"
+)
+
+var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
+
+// Поиск работает по колонкам search_vector (см. internal/database/postgresql/search.go),
+// поэтому требует PostgreSQL.
+
+// Запрос в синтаксисе веб-поиска: слова, "фразы", OR, -исключение
+const searchTsQuery = "websearch_to_tsquery('russian', ?)"
+
+// Границы совпадений во фрагментах ts_headline. Текст дефектов и комментариев пишут
+// пользователи, поэтому фрагмент сначала экранируется как HTML, и только потом
+// управляющие символы заменяются на <mark> (см. markHighlight).
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// Параметры ts_headline; передаются в запрос параметром, а не вставляются в текст SQL
+const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop +
+	", MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// Запрос и параметры подсветки одной строкой, к которой присоединяются дефекты
+const searchJoin = "CROSS JOIN (SELECT " + searchTsQuery + " AS query, CAST(? AS text) AS headline_options) AS search"
+
+// Сколько фрагментов из комментариев показывать на один дефект
+const maxCommentHighlights = 3
+
+type SearchHandler struct {
+	db       *gorm.DB
+	workflow *workflow.Workflow
+}
+
+func NewSearchHandler(db *gorm.DB, wf *workflow.Workflow) *SearchHandler {
+	return &SearchHandler{db: db, workflow: wf}
+}
+
+// SearchHighlight фрагмент с подсвеченным совпадением (<mark>…</mark>); остальной текст
+// фрагмента экранирован как HTML.
+// swagger:model SearchHighlight
+type SearchHighlight struct {
+	// title, description, comment, building_name или building_address
+	// example: description
+	Field     string `json:"field"`
+	// только для field = comment
+	// example: 12
+	CommentID *uint  `json:"comment_id,omitempty"`
+	// example: Протечка на <mark>5 этаже</mark> у лифта
+	Fragment  string `json:"fragment"`
+}
+
+// SearchResult найденный дефект с релевантностью и фрагментами.
+// swagger:model SearchResult
+type SearchResult struct {
+	Defect     DefectResponse    `json:"defect"`
+	// example: 0.75
+	Rank       float64           `json:"rank"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+// Search searches defects by text in defects, their comments and buildings.
+// @Summary     Full-text search
+// @Description Full-text search (Russian and English stemming) over defect title and description, comment text and building name and address. Results are defects ordered by relevance, with highlighted fragments. Accepts the same filters as the defect list. q supports web search syntax: "phrase", OR, -exclude.
+// @Tags        search
+// @Accept      json
+// @Produce     json
+// @Param       q              query     string  true   "Search query"
+// @Param       status         query     string  false  "Filter by status, comma-separated list allowed"
+// @Param       priority       query     string  false  "Filter by priority (low, medium, high), comma-separated list allowed"
+// @Param       building_id    query     int     false  "Filter by building id"
+// @Param       responsible_id query     int     false  "Filter by responsible user id"
+// @Param       created_by_id  query     int     false  "Filter by creator user id"
+// @Param       created_from   query     string  false  "Created at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       created_to     query     string  false  "Created at or before (2006-01-02 includes the whole day)"
+// @Param       deadline_from  query     string  false  "Deadline at or after (2006-01-02 or 2006-01-02 15:04:05)"
+// @Param       deadline_to    query     string  false  "Deadline at or before (2006-01-02 includes the whole day)"
+// @Param       overdue        query     bool    false  "Only open defects with a past deadline"
+// @Param       unassigned     query     bool    false  "Only defects without a responsible user"
+// @Param       limit          query     int     false  "Limit number of results (default 100)"
+// @Param       offset         query     int     false  "Offset for pagination (default 0)"
+// @Success     200  {object}  ListResponse[SearchResult]
+// @Failure     400  {object}  common.ErrorResponse  "missing q or invalid query param"
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/search [get]
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	if err := checkQueryParams(c, append(defectFilterParams, "q", "limit", "offset")...); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q parameter is required"})
+	}
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// результаты упорядочены по релевантности, курсор по (created_at, id) к ним неприменим
+	if page.After != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cursor is not supported for search, use offset"})
+	}
+
+	filtered, err := applyDefectFilters(c, h.db.Model(&models.Defect{}), h.workflow)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// совпадение в самом дефекте, его здании или хотя бы одном комментарии
+	matches := filtered.
+		Joins(searchJoin, text, headlineOptions).
+		Joins("JOIN buildings ON buildings.id = defects.building_id AND buildings.deleted_at IS NULL").
+		Joins("LEFT JOIN (SELECT comments.defect_id, MAX(ts_rank(comments.search_vector, "+searchTsQuery+")) AS comment_rank "+
+			"FROM comments WHERE comments.deleted_at IS NULL AND comments.search_vector @@ "+searchTsQuery+" "+
+			"GROUP BY comments.defect_id) AS comment_hits ON comment_hits.defect_id = defects.id", text, text).
+		Where("(defects.search_vector @@ search.query OR buildings.search_vector @@ search.query OR comment_hits.defect_id IS NOT NULL)").
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := matches.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var hits []struct {
+		ID   uint
+		Rank float64
+	}
+	if err := matches.
+		Select("defects.id, ts_rank(defects.search_vector, search.query) + " +
+			"ts_rank(buildings.search_vector, search.query) + COALESCE(comment_hits.comment_rank, 0) AS rank").
+		Order("rank DESC").Order("defects.id").
+		Limit(page.Limit).Offset(page.Offset).
+		Scan(&hits).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[SearchResult]{Items: make([]SearchResult, 0, len(hits)), Total: total}
+	if len(hits) == 0 {
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	var defects []models.Defect
+	if err := preloadDefect(h.db).Where("defects.id IN ?", ids).Find(&defects).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+	byID := make(map[uint]models.Defect, len(defects))
+	for _, d := range defects {
+		byID[d.ID] = d
+	}
+
+	highlights, err := h.highlights(text, ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	for _, hit := range hits {
+		d, ok := byID[hit.ID]
+		if !ok {
+			continue // удалён между запросами
+		}
+		hl := highlights[hit.ID]
+		if hl == nil {
+			hl = []SearchHighlight{}
+		}
+		resp.Items = append(resp.Items, SearchResult{Defect: toDefectResponse(d, h.workflow), Rank: hit.Rank, Highlights: hl})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// highlights строит фрагменты с подсветкой для найденных дефектов: по полям дефекта и здания,
+// в которых есть совпадение, и по самым релевантным комментариям.
+func (h *SearchHandler) highlights(text string, defectIDs []uint) (map[uint][]SearchHighlight, error) {
+	// headline строит фрагмент колонки column, только если в ней есть совпадение
+	headline := func(column, alias string) string {
+		return "CASE WHEN to_tsvector('russian', coalesce(" + column + ", '')) @@ search.query " +
+			"THEN ts_headline('russian', " + column + ", search.query, search.headline_options) END AS " + alias
+	}
+
+	var fields []struct {
+		ID              uint
+		Title           *string
+		Description     *string
+		BuildingName    *string
+		BuildingAddress *string
+	}
+	if err := h.db.Table("defects").
+		Select("defects.id, "+
+			headline("defects.title", "title")+", "+
+			headline("defects.description", "description")+", "+
+			headline("buildings.name", "building_name")+", "+
+			headline("buildings.address", "building_address")).
+		Joins(searchJoin, text, headlineOptions).
+		Joins("JOIN buildings ON buildings.id = defects.building_id").
+		Where("defects.id IN ?", defectIDs).
+		Scan(&fields).Error; err != nil {
+		return nil, err
+	}
+
+	res := make(map[uint][]SearchHighlight, len(defectIDs))
+	add := func(defectID uint, field string, fragment *string, commentID *uint) {
+		if fragment != nil {
+			res[defectID] = append(res[defectID], SearchHighlight{Field: field, CommentID: commentID, Fragment: markHighlight(*fragment)})
+		}
+	}
+	for _, f := range fields {
+		add(f.ID, "title", f.Title, nil)
+		add(f.ID, "description", f.Description, nil)
+		add(f.ID, "building_name", f.BuildingName, nil)
+		add(f.ID, "building_address", f.BuildingAddress, nil)
+	}
+
+	var comments []struct {
+		ID       uint
+		DefectID uint
+		Fragment string
+	}
+	if err := h.db.Model(&models.Comment{}).
+		Select("comments.id, comments.defect_id, ts_headline('russian', comments.text, search.query, search.headline_options) AS fragment").
+		Joins(searchJoin, text, headlineOptions).
+		Where("comments.defect_id IN ? AND comments.search_vector @@ search.query", defectIDs).
+		Order("ts_rank(comments.search_vector, search.query) DESC").Order("comments.id").
+		Scan(&comments).Error; err != nil {
+		return nil, err
+	}
+	perDefect := make(map[uint]int)
+	for _, cm := range comments {
+		if perDefect[cm.DefectID] >= maxCommentHighlights {
+			continue
+		}
+		perDefect[cm.DefectID]++
+		id := cm.ID
+		add(cm.DefectID, "comment", &cm.Fragment, &id)
+	}
+
+	return res, nil
+}
+
+// markHighlight экранирует фрагмент ts_headline как HTML и заменяет границы совпадений на <mark>.
+// Управляющие символы границ в самом тексте тоже станут <mark> или </mark>, но не
+// произвольной разметкой.
+func markHighlight(fragment string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(html.EscapeString(fragment))
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Общие помощники для мягкого удаления. Модели с полями DeletedAt (gorm.DeletedAt)
+// и DeletedByPersonID автоматически исключаются из обычных запросов GORM,
+// поэтому корзина, восстановление и окончательное удаление работают через Unscoped.
+
+// DeletionInfo описывает, когда и кем запись была перемещена в корзину.
+// swagger:model DeletionInfo
+type DeletionInfo struct {
+	// example: 2025-10-11T14:00:00Z
+	DeletedAt time.Time `json:"deleted_at"`
+	// example: 1
+	DeletedByPersonID *uint `json:"deleted_by_person_id"`
+}
+
+func toDeletionInfo(deletedAt gorm.DeletedAt, deletedBy *uint) DeletionInfo {
+	return DeletionInfo{DeletedAt: deletedAt.Time, DeletedByPersonID: deletedBy}
+}
+
+// softDelete помечает запись удалённой и запоминает, кто её удалил.
+// model должен быть загруженной записью с заполненным первичным ключом.
+func softDelete(db *gorm.DB, model interface{}, uid uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// UpdateColumn, чтобы не трогать updated_at
+		if err := tx.Model(model).UpdateColumn("deleted_by_person_id", uid).Error; err != nil {
+			return err
+		}
+		return tx.Delete(model).Error
+	})
+}
+
+// restoreDeleted возвращает запись из корзины.
+func restoreDeleted(db *gorm.DB, model interface{}) error {
+	return db.Unscoped().Model(model).UpdateColumns(map[string]interface{}{
+		"deleted_at":           nil,
+		"deleted_by_person_id": nil,
+	}).Error
+}
+
+// findDeleted загружает запись из корзины (только мягко удалённые записи).
+func findDeleted(db *gorm.DB, dest interface{}, id int) error {
+	return db.Unscoped().Where("deleted_at IS NOT NULL").First(dest, id).Error
+}
+
+// trashScope — записи корзины: только мягко удалённые. Без порядка, годится и для подсчёта total.
+func trashScope(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// trashOrder — страница корзины таблицы table: последние удалённые первыми,
+// курсор по (deleted_at, id).
+func trashOrder(q *gorm.DB, table string, p pageParams) *gorm.DB {
+	return keysetOrderBy(q, table, "deleted_at", true, p)
+}
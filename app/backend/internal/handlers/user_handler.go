@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strconv"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/models"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -14,11 +15,19 @@ import (
 var _ = common.ErrorResponse{} // костыль для swagger: без этой строчки будет ../common imported and not used
 
 type UserHandler struct {
-    db *gorm.DB
+    db     *gorm.DB
+    tokens *auth.Manager
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
-    return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, tokens *auth.Manager) *UserHandler {
+    return &UserHandler{db: db, tokens: tokens}
+}
+
+// допустимые роли пользователей
+var userRoles = map[string]struct{}{
+	"engineer": {},
+	"manager":  {},
+	"observer": {},
 }
 
 // CreateUserRequest represents request body to create a user.
@@ -63,6 +72,9 @@ type UpdateUserRequest struct {
     Name     string `json:"name"`
     // example: Ivanov
     LastName string `json:"lastname"`
+    // new role; changing it revokes all user's sessions
+    // example: manager
+    Role     string `json:"role"`
 }
 
 // CreateUser creates a new user.
@@ -89,7 +101,8 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 
     // проверка уникальности (уникальность также проверяется через тег unique в модели user)
     var cnt int64
-    if err := h.db.Model(&models.User{}).Where("login = ?", req.Login).Count(&cnt).Error; err != nil {
+    // Unscoped: логин удалённого пользователя остаётся занятым (на случай восстановления)
+    if err := h.db.Unscoped().Model(&models.User{}).Where("login = ?", req.Login).Count(&cnt).Error; err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
     }
     if cnt > 0 {
@@ -119,23 +132,37 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 
 // GetUsers returns list of users.
 // @Summary     Get users
-// @Description Retrieve list of all users
+// @Description Retrieve users page by page (offset or keyset cursor on created_at, id)
 // @Tags        users
 // @Accept      json
 // @Produce     json
-// @Success     200   {array}   UserResponse
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200   {object}  ListResponse[UserResponse]
+// @Failure     400   {object}  common.ErrorResponse  "invalid query param"
 // @Failure     500   {object}  common.ErrorResponse
 // @Security    BearerAuth
 // @Router      /api/users [get]
 func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
-	users := []models.User{}
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	if err := h.db.Find(&users).Error; err != nil {
+	users := []models.User{}
+	q := keysetOrder(h.db.Model(&models.User{}), "users", false, page)
+	total, hasMore, err := findPage(h.db.Model(&models.User{}), q, page, &users)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 	}
-	resp := make([]UserResponse, 0, len(users))
+	resp := ListResponse[UserResponse]{Items: make([]UserResponse, 0, len(users)), Total: total}
 	for _, u := range users {
-		resp = append(resp, CreateResponseUser(u))
+		resp.Items = append(resp.Items, CreateResponseUser(u))
+	}
+	if hasMore {
+		last := users[len(users)-1]
+		resp.NextCursor = nextCursor(true, last.CreatedAt, last.ID)
 	}
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
@@ -173,9 +200,9 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(CreateResponseUser(user))
 }
 
-// UpdateUser updates user's name/lastname.
+// UpdateUser updates user's name/lastname/role.
 // @Summary     Update user
-// @Description Update user's name and/or lastname
+// @Description Update user's name, lastname and/or role. Changing the role revokes all user's sessions, so the user has to log in again; the new role applies to the user's requests immediately
 // @Tags        users
 // @Accept      json
 // @Produce     json
@@ -208,12 +235,7 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 	
-	type UpdateUserReq struct {
-		Name string `json:"name"`
-		LastName string `json:"lastname"`
-	}
-
-	var req UpdateUserReq
+	var req UpdateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -223,17 +245,37 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	if req.LastName != "" {
 		user.LastName = req.LastName
 	}
+	roleChanged := false
+	if req.Role != "" && req.Role != user.Role {
+		if _, ok := userRoles[req.Role]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid role"})
+		}
+		user.Role = req.Role
+		roleChanged = true
+	}
 
-	if err := h.db.Save(&user).Error; err != nil {
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		// токены хранят роль, поэтому при её смене все сеансы отзываются
+		if roleChanged {
+			return h.tokens.RevokeUserSessions(tx, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save"})
 	}
+	// следующий запрос пользователя перечитает роль из БД
+	h.tokens.InvalidateUser(user.ID)
 
 	return c.Status(fiber.StatusOK).JSON(CreateResponseUser(user))
 }
 
-// DeleteUser deletes user by id.
+// DeleteUser moves user to trash.
 // @Summary     Delete user
-// @Description Delete user by numeric id
+// @Description Soft-delete user by numeric id. A deleted user cannot log in and all the user's sessions are revoked; the user can be restored or purged from trash.
 // @Tags        users
 // @Accept      json
 // @Produce     plain
@@ -265,11 +307,24 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.db.Delete(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "user not found",
+	uid, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
+	}
+
+	// удалённый пользователь теряет все сеансы сразу, а не по истечении токенов
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := softDelete(tx, &user, uid); err != nil {
+			return err
+		}
+		return h.tokens.RevokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete user",
 		})
 	}
+	h.tokens.InvalidateUser(user.ID)
 
 	return c.Status(fiber.StatusOK).SendString("Successfully deleted user with id " + strconv.Itoa(int(user.ID)) )
 }
@@ -285,4 +340,158 @@ func (h *UserHandler) GetUserByCtx(c *fiber.Ctx) error {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "db error"})
     }
     return c.Status(fiber.StatusOK).JSON(CreateResponseUser(user))
-}
\ No newline at end of file
+}
+
+// DeletedUserResponse describes a user in trash.
+// swagger:model DeletedUserResponse
+type DeletedUserResponse struct {
+	UserResponse
+	DeletionInfo
+}
+
+// GetDeletedUsers returns users in trash.
+// @Summary     List deleted users
+// @Description Retrieve soft-deleted users page by page, most recently deleted first (offset or keyset cursor on deleted_at, id)
+// @Tags        users
+// @Accept      json
+// @Produce     json
+// @Param       limit   query     int     false  "Limit number of results (default 100)"
+// @Param       offset  query     int     false  "Offset for pagination (default 0)"
+// @Param       cursor  query     string  false  "next_cursor from the previous page (not combinable with offset)"
+// @Success     200   {object}  ListResponse[DeletedUserResponse]
+// @Failure     400   {object}  common.ErrorResponse  "invalid query param"
+// @Failure     401   {object}  common.ErrorResponse
+// @Failure     403   {object}  common.ErrorResponse
+// @Failure     500   {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/users/trash [get]
+func (h *UserHandler) GetDeletedUsers(c *fiber.Ctx) error {
+	page, err := parsePageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var users []models.User
+	q := trashOrder(trashScope(h.db.Model(&models.User{})), "users", page)
+	total, hasMore, err := findPage(trashScope(h.db.Model(&models.User{})), q, page, &users)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	resp := ListResponse[DeletedUserResponse]{Items: make([]DeletedUserResponse, 0, len(users)), Total: total}
+	for _, u := range users {
+		resp.Items = append(resp.Items, DeletedUserResponse{
+			UserResponse: CreateResponseUser(u),
+			DeletionInfo: toDeletionInfo(u.DeletedAt, u.DeletedByPersonID),
+		})
+	}
+	if hasMore {
+		last := users[len(users)-1]
+		resp.NextCursor = nextCursor(true, last.DeletedAt.Time, last.ID)
+	}
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RestoreUser restores user from trash.
+// @Summary     Restore user
+// @Description Restore a soft-deleted user
+// @Tags        users
+// @Accept      json
+// @Produce     json
+// @Param       id   path      int  true  "User ID"
+// @Success     200  {object}  UserResponse
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted user not found"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var user models.User
+	if err := findDeleted(h.db, &user, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	if err := restoreDeleted(h.db, &user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to restore user"})
+	}
+	h.tokens.InvalidateUser(user.ID)
+
+	return c.Status(fiber.StatusOK).JSON(CreateResponseUser(user))
+}
+
+// PurgeUser permanently deletes a user from trash.
+// @Summary     Purge user
+// @Description Permanently delete a soft-deleted user. Not allowed while defects, comments or history entries (including deleted ones) reference the user.
+// @Tags        users
+// @Accept      json
+// @Produce     plain
+// @Param       id   path      int  true  "User ID"
+// @Success     200  {string}  string  "Permanently deleted user with id {id}"
+// @Failure     400  {object}  common.ErrorResponse
+// @Failure     401  {object}  common.ErrorResponse
+// @Failure     403  {object}  common.ErrorResponse
+// @Failure     404  {object}  common.ErrorResponse  "deleted user not found"
+// @Failure     409  {object}  common.ErrorResponse  "user is still referenced"
+// @Failure     500  {object}  common.ErrorResponse
+// @Security    BearerAuth
+// @Router      /api/users/{id}/purge [delete]
+func (h *UserHandler) PurgeUser(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	var user models.User
+	if err := findDeleted(h.db, &user, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "deleted user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+	}
+
+	// пользователь, на которого ссылаются дефекты, комментарии или история, нужен как свидетельство
+	refs := []struct {
+		model interface{}
+		where string
+	}{
+		{&models.Defect{}, "created_by_person_id = @id OR updated_by_person_id = @id OR responsible_person_id = @id"},
+		{&models.Comment{}, "created_by_person_id = @id"},
+		{&models.DefectHistory{}, "changed_by_person_id = @id"},
+	}
+	for _, ref := range refs {
+		var cnt int64
+		if err := h.db.Unscoped().Model(ref.model).Where(ref.where, map[string]interface{}{"id": user.ID}).Count(&cnt).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
+		}
+		if cnt > 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "user is still referenced by defects, comments or history"})
+		}
+	}
+
+	// упоминания — не свидетельство, удаляются вместе с пользователем
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&user).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to purge user"})
+	}
+	h.tokens.InvalidateUser(user.ID)
+
+	return c.Status(fiber.StatusOK).SendString("Permanently deleted user with id " + strconv.Itoa(int(user.ID)))
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefectChanges сравнивает два состояния дефекта и возвращает по записи на каждое изменённое поле.
+// Для создания дефекта before — нулевое значение models.Defect{}.
+func DefectChanges(before, after models.Defect, actorID uint, at time.Time) []models.DefectHistory {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"building_id", formatID(before.BuildingID), formatID(after.BuildingID)},
+		{"title", before.Title, after.Title},
+		{"description", before.Description, after.Description},
+		{"priority", before.Priority, after.Priority},
+		{"responsible_person_id", formatID(before.ResponsiblePersonID), formatID(after.ResponsiblePersonID)},
+		{"deadline", formatTime(before.Deadline), formatTime(after.Deadline)},
+		{"status", before.Status, after.Status},
+	}
+
+	var changes []models.DefectHistory
+	for _, f := range fields {
+		if f.old == f.new {
+			continue
+		}
+		changes = append(changes, models.DefectHistory{
+			DefectID:          after.ID,
+			Field:             f.name,
+			OldValue:          f.old,
+			NewValue:          f.new,
+			ChangedByPersonID: actorID,
+			ChangedAt:         at,
+		})
+	}
+	return changes
+}
+
+// Deleted запись о перемещении дефекта в корзину: поле deleted_at меняется с пустого на момент удаления.
+// Кто удалил, остаётся в истории и после восстановления, когда deleted_by_person_id очищается.
+func Deleted(defectID, actorID uint, at time.Time) []models.DefectHistory {
+	return []models.DefectHistory{{
+		DefectID:          defectID,
+		Field:             "deleted_at",
+		NewValue:          formatTime(at),
+		ChangedByPersonID: actorID,
+		ChangedAt:         at,
+	}}
+}
+
+// Restored запись о восстановлении дефекта из корзины, удалённого в момент deletedAt.
+func Restored(defectID uint, deletedAt time.Time, actorID uint, at time.Time) []models.DefectHistory {
+	return []models.DefectHistory{{
+		DefectID:          defectID,
+		Field:             "deleted_at",
+		OldValue:          formatTime(deletedAt),
+		ChangedByPersonID: actorID,
+		ChangedAt:         at,
+	}}
+}
+
+// Save сохраняет записи истории в рамках переданной транзакции.
+func Save(tx *gorm.DB, changes []models.DefectHistory) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.Omit("Defect", "ChangedBy").Create(&changes).Error
+}
+
+// EnterStatus фиксирует вход дефекта в статус: закрывает текущий интервал статуса
+// (если он есть) и открывает новый. Вызывается внутри транзакции при создании
+// дефекта и при каждой смене статуса.
+func EnterStatus(tx *gorm.DB, defectID uint, status string, actorID uint, at time.Time) error {
+	if err := tx.Model(&models.DefectStatusPeriod{}).
+		Where("defect_id = ? AND left_at IS NULL", defectID).
+		Update("left_at", at).Error; err != nil {
+		return err
+	}
+	return tx.Omit("Defect").Create(&models.DefectStatusPeriod{
+		DefectID:          defectID,
+		Status:            status,
+		EnteredAt:         at,
+		EnteredByPersonID: actorID,
+	}).Error
+}
+
+// 0 означает "не задано" (например, дефект без ответственного)
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateTime)
+}
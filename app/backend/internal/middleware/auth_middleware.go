@@ -1,56 +1,48 @@
 package middleware
 
 import (
-	"strconv"
+	"errors"
 	"strings"
 
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTMiddleware(secret string) fiber.Handler {
+// JWTMiddleware пропускает запрос с действующим access-токеном неотозванного сеанса
+// и кладёт в locals user_id, role и session_id. Роль — текущая из БД (через кэш
+// auth.Manager), а не из claims: понижение роли действует сразу.
+func JWTMiddleware(tokens *auth.Manager) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// get token from request headers
-		auth := c.Get("Authorization")
-		if auth == "" {
+		header := c.Get("Authorization")
+		if header == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing Authorization header"})
 		}
 		// split "Bearer" and token itself
-		parts := strings.SplitN(auth, " ", 2)
+		parts := strings.SplitN(header, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid Authorization header"})
 		}
-		tokenStr := parts[1]
 
-		// parse token
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			// validate signing method if needed
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token claims"})
-		}
-
-		// read sub (user id)
-		sub, ok := claims["sub"].(string)
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token subject"})
-		}
-		uid, err := strconv.ParseUint(sub, 10, 64)
+		// parse token and check its session
+		identity, err := tokens.Authenticate(parts[1])
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token subject"})
+			if errors.Is(err, auth.ErrSessionRevoked) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session revoked"})
+			}
+			if errors.Is(err, auth.ErrUserNotFound) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not found"})
+			}
+			if errors.Is(err, auth.ErrInvalidToken) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database error"})
 		}
 
 		// set locals for handlers
-		c.Locals("user_id", uint(uid))
-		if role, ok := claims["role"].(string); ok {
-			c.Locals("role", role)
-		}
+		c.Locals("user_id", identity.UserID)
+		c.Locals("role", identity.Role)
+		c.Locals("session_id", identity.SessionID)
 		return c.Next()
 	}
 }
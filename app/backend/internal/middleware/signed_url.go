@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SignedURLOrJWT пропускает запрос с действующей подписью ссылки (?expires=…&signature=…)
+// или, если подписи нет, с JWT в заголовке Authorization.
+// Для подписанной ссылки user_id и role не выставляются, а в locals кладётся signed_url = true.
+func SignedURLOrJWT(tokens *auth.Manager, signer *signedurl.Signer) fiber.Handler {
+	jwtMiddleware := JWTMiddleware(tokens)
+
+	return func(c *fiber.Ctx) error {
+		signature := c.Query("signature")
+		if signature == "" {
+			return jwtMiddleware(c)
+		}
+
+		if err := signer.Verify(c.Path(), c.Query("expires"), signature); err != nil {
+			if errors.Is(err, signedurl.ErrExpired) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "link has expired"})
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invalid link signature"})
+		}
+
+		c.Locals("signed_url", true)
+		return c.Next()
+	}
+}
@@ -5,4 +5,9 @@ type CommentAttachment struct {
 	CommentID uint    `json:"comment_id"`
 	Comment   Comment `json:"-" gorm:"foreignKey:CommentID"`
 	URL       string  `json:"url"`
-}
\ No newline at end of file
+	// SHA-256 содержимого (models.StoredFile); пусто у вложений, загруженных до дедупликации
+	Hash        string `json:"hash" gorm:"index;size:64"`
+	FileName    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
@@ -1,8 +1,22 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Building struct {
 	ID      uint   `json:"id" gorm:"primaryKey"`
 	Name    string `json:"name" gorm:"not null"`
 	Address string `json:"address"`
 	Stage   string `json:"stage"`
+	// координаты объекта: с ними сверяется геотег фото вложений
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// default заполняет колонку у записей, созданных до её появления
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedByPersonID *uint          `json:"-"`
 }
\ No newline at end of file
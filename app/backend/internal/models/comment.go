@@ -1,14 +1,25 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Comment struct {
 	ID                uint              `json:"id" gorm:"primaryKey"`
 	DefectID          uint              `json:"defect_id"`
 	Defect            Defect            `json:"-" gorm:"foreignKey:DefectID"`
+	// комментарий, на который это ответ; nil — комментарий верхнего уровня
+	ParentID          *uint             `json:"parent_id" gorm:"index"`
 	CreatedAt         time.Time         `json:"created_at"`
 	CreatedByPersonID uint              `json:"created_by_person_id"`
 	CreatedBy         User              `json:"created_by" gorm:"foreignKey:CreatedByPersonID"`
 	Text              string            `json:"text"`
+	EditedAt          *time.Time        `json:"edited_at"`
 	Attachments       []CommentAttachment `json:"attachments"`
+	Mentions          []CommentMention    `json:"mentions"`
+
+	DeletedAt         gorm.DeletedAt    `json:"-" gorm:"index"`
+	DeletedByPersonID *uint             `json:"-"`
 }
\ No newline at end of file
@@ -0,0 +1,10 @@
+package models
+
+// CommentMention — упоминание пользователя (@login) в тексте комментария.
+type CommentMention struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	CommentID uint    `json:"comment_id" gorm:"uniqueIndex:idx_comment_mentions_comment_user"`
+	Comment   Comment `json:"-" gorm:"foreignKey:CommentID"`
+	UserID    uint    `json:"user_id" gorm:"uniqueIndex:idx_comment_mentions_comment_user;index"`
+	User      User    `json:"user" gorm:"foreignKey:UserID"`
+}
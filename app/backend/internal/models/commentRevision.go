@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CommentRevision — предыдущая версия текста комментария, сохраняется при каждом редактировании.
+type CommentRevision struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	CommentID uint    `json:"comment_id" gorm:"index"`
+	Comment   Comment `json:"-" gorm:"foreignKey:CommentID"`
+	Text      string  `json:"text"`
+	// когда эта версия была написана (создание комментария или предыдущая правка)
+	WrittenAt time.Time `json:"written_at"`
+	// когда эту версию заменили новой
+	ReplacedAt       time.Time `json:"replaced_at"`
+	EditedByPersonID uint      `json:"edited_by_person_id"`
+	EditedBy         User      `json:"-" gorm:"foreignKey:EditedByPersonID"`
+}
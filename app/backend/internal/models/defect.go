@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Defect struct {
 	ID                  uint      `json:"id" gorm:"primaryKey"`
@@ -21,4 +25,8 @@ type Defect struct {
 	Status              string    `json:"status"`     // new, in_progress, review, closed, cancelled
 	Attachments         []DefectAttachment `json:"attachments"`
 	Comments            []Comment          `json:"comments"`
+	StatusPeriods       []DefectStatusPeriod `json:"status_periods"`
+
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedByPersonID   *uint          `json:"-"`
 }
\ No newline at end of file
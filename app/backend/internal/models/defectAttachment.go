@@ -1,8 +1,27 @@
 package models
 
+import "time"
+
 type DefectAttachment struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`
 	DefectID uint   `json:"defect_id"`
 	Defect   Defect `json:"-" gorm:"foreignKey:DefectID"`
 	URL      string `json:"url"`
-}
\ No newline at end of file
+	// SHA-256 содержимого (models.StoredFile); пусто у вложений, загруженных до дедупликации
+	Hash        string `json:"hash" gorm:"index;size:64"`
+	FileName    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+
+	// уменьшенные копии фото (ключи в хранилище), строятся в фоне после загрузки
+	ThumbnailKey  string `json:"thumbnail_key"`
+	PreviewKey    string `json:"preview_key"`
+	PreviewStatus string `json:"preview_status" gorm:"default:pending"`
+
+	// метаданные EXIF фото: время съёмки, устройство, ориентация и геотег
+	TakenAt     *time.Time `json:"taken_at"`
+	Device      string     `json:"device"`
+	Orientation int        `json:"orientation"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+}
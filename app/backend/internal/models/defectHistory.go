@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DefectHistory — запись об изменении одного поля дефекта
+type DefectHistory struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	DefectID          uint      `json:"defect_id" gorm:"index"`
+	Defect            Defect    `json:"-" gorm:"foreignKey:DefectID"`
+	Field             string    `json:"field" gorm:"not null"`
+	OldValue          string    `json:"old_value"`
+	NewValue          string    `json:"new_value"`
+	ChangedByPersonID uint      `json:"changed_by_person_id"`
+	ChangedBy         User      `json:"changed_by" gorm:"foreignKey:ChangedByPersonID"`
+	ChangedAt         time.Time `json:"changed_at" gorm:"index"`
+}
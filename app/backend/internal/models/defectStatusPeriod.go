@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// DefectStatusPeriod — интервал, в течение которого дефект находился в статусе.
+// LeftAt == nil у текущего статуса.
+type DefectStatusPeriod struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	DefectID          uint       `json:"defect_id" gorm:"index"`
+	Defect            Defect     `json:"-" gorm:"foreignKey:DefectID"`
+	Status            string     `json:"status" gorm:"not null"`
+	EnteredAt         time.Time  `json:"entered_at" gorm:"not null"`
+	LeftAt            *time.Time `json:"left_at"`
+	EnteredByPersonID uint       `json:"entered_by_person_id"`
+}
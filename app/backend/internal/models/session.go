@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session сеанс входа пользователя. Хранит хэш текущего refresh-токена: при каждом
+// обновлении токен меняется, а предъявление старого отзывает весь сеанс.
+type Session struct {
+	// случайный идентификатор, он же claim sid в access-токене
+	ID     string `json:"id" gorm:"primaryKey;size:32"`
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	// SHA-256 секретной части refresh-токена в hex
+	RefreshTokenHash string `json:"-" gorm:"size:64;not null"`
+	UserAgent        string `json:"user_agent" gorm:"size:255"`
+	IP               string `json:"ip" gorm:"size:64"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
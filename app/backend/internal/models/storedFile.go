@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StoredFile объект в хранилище, общий для всех вложений (дефектов и комментариев)
+// с одинаковым содержимым. Удаляется вместе с файлом, когда уходит последняя ссылка.
+type StoredFile struct {
+	// SHA-256 содержимого в hex
+	Hash        string `json:"hash" gorm:"primaryKey;size:64"`
+	Key         string `json:"key" gorm:"not null"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	// сколько вложений ссылается на файл
+	RefCount  int       `json:"ref_count" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`
 	Login    string `json:"login" gorm:"size:100;not null;unique"`
@@ -7,4 +13,9 @@ type User struct {
 	Name     string `json:"name" gorm:"not null"`
 	LastName string `json:"lastname" gorm:"not null"`
 	Role     string `json:"role" gorm:"not null"` // engineer, manager, observer
+	// default заполняет колонку у записей, созданных до её появления
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedByPersonID *uint          `json:"-"`
 }
\ No newline at end of file
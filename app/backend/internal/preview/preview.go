@@ -0,0 +1,146 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Уменьшенные копии фото вложений: миниатюра для карточек и списка дефектов и превью
+// для страницы дефекта. Копии всегда сохраняются в JPEG рядом с оригиналом.
+
+// Variant размер уменьшенной копии.
+type Variant struct {
+	// суффикс ключа: "<ключ оригинала без расширения>_<Name>.jpg"
+	Name string
+	// ограничение по большей стороне, px
+	MaxSide int
+}
+
+var (
+	Thumbnail = Variant{Name: "thumb", MaxSide: 320}
+	Medium    = Variant{Name: "preview", MaxSide: 1280}
+)
+
+// Статусы генерации копий у вложения.
+const (
+	StatusPending = "pending" // ждёт генерации
+	StatusReady   = "ready"   // копии сохранены
+	StatusFailed  = "failed"  // не удалось прочитать или сохранить
+	StatusNone    = "none"    // не изображение или формат без декодера (например, HEIC)
+)
+
+// maxPixels защита от "бомб": огромное изображение при декодировании займёт гигабайты памяти
+const maxPixels = 50_000_000
+
+const jpegQuality = 82
+
+var ErrTooManyPixels = errors.New("image is too large to render")
+
+// Supported можно ли построить копии для файла этого типа.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Key ключ копии в хранилище рядом с оригиналом.
+func Key(originalKey string, v Variant) string {
+	return strings.TrimSuffix(originalKey, path.Ext(originalKey)) + "_" + v.Name + ".jpg"
+}
+
+// Decode декодирует изображение, заранее проверяя его размеры.
+func Decode(r io.ReadSeeker) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooManyPixels, cfg.Width, cfg.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Resize уменьшает изображение под вариант; меньшие изображения не увеличиваются.
+// Прозрачные области заливаются белым, чтобы в JPEG не было чёрного фона.
+func Resize(img image.Image, v Variant) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > v.MaxSide || h > v.MaxSide {
+		if w >= h {
+			w, h = v.MaxSide, max(1, h*v.MaxSide/w)
+		} else {
+			w, h = max(1, w*v.MaxSide/h), v.MaxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// Encode кодирует копию в JPEG.
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Orient поворачивает и отражает изображение по тегу EXIF Orientation (2..8), чтобы копия
+// в JPEG без метаданных выглядела так же, как оригинал в браузере.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				dx, dy = w-1-x, y
+			case 3: // поворот на 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // отражение по вертикали
+				dx, dy = x, h-1-y
+			case 5: // транспонирование
+				dx, dy = y, x
+			case 6: // поворот на 90° по часовой
+				dx, dy = h-1-y, x
+			case 7: // поперечное отражение
+				dx, dy = h-1-y, w-1-x
+			case 8: // поворот на 90° против часовой
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
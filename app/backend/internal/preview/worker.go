@@ -0,0 +1,158 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/models"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// queueSize сколько загрузок может ждать генерации. Если очередь переполнена, вложение
+// остаётся в статусе pending и его подберёт команда cmd/previews.
+const queueSize = 256
+
+// Worker строит копии фото вложений дефектов в фоне, чтобы загрузка не ждала ресайза.
+type Worker struct {
+	db    *gorm.DB
+	store storage.Storage
+	queue chan uint
+	wg    sync.WaitGroup
+}
+
+func NewWorker(db *gorm.DB, store storage.Storage) *Worker {
+	return &Worker{db: db, store: store, queue: make(chan uint, queueSize)}
+}
+
+// Start запускает n горутин, обрабатывающих очередь, пока не отменён ctx.
+func (w *Worker) Start(ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-w.queue:
+					if err := w.Process(ctx, id); err != nil {
+						log.Warn().Err(err).Uint("attachment_id", id).Msg("failed to generate attachment previews")
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Wait ждёт завершения горутин после отмены контекста Start.
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
+
+// Enqueue ставит вложение дефекта в очередь, не блокируя запрос.
+func (w *Worker) Enqueue(attachmentID uint) {
+	select {
+	case w.queue <- attachmentID:
+	default:
+		log.Warn().Uint("attachment_id", attachmentID).Msg("preview queue is full, leaving attachment pending")
+	}
+}
+
+// Process строит и сохраняет копии для вложения и обновляет его статус. Ошибка чтения
+// или декодирования файла переводит вложение в failed.
+func (w *Worker) Process(ctx context.Context, attachmentID uint) error {
+	var a models.DefectAttachment
+	if err := w.db.WithContext(ctx).First(&a, attachmentID).Error; err != nil {
+		// вложение могли удалить, пока оно ждало в очереди
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if !Supported(a.ContentType) {
+		return w.setStatus(ctx, a.ID, StatusNone)
+	}
+
+	keys, err := w.render(ctx, a.URL, a.Orientation)
+	if err != nil {
+		if serr := w.setStatus(ctx, a.ID, StatusFailed); serr != nil {
+			log.Warn().Err(serr).Uint("attachment_id", a.ID).Msg("failed to mark attachment previews as failed")
+		}
+		return fmt.Errorf("attachment %d: %w", a.ID, err)
+	}
+
+	res := w.db.WithContext(ctx).Model(&models.DefectAttachment{}).Where("id = ?", a.ID).Updates(map[string]any{
+		"thumbnail_key":  keys[0],
+		"preview_key":    keys[1],
+		"preview_status": StatusReady,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	// вложение удалили, пока строились копии — файлы копий больше никому не нужны,
+	// если сам файл не используется другими вложениями (models.StoredFile)
+	if res.RowsAffected == 0 {
+		var shared int64
+		if err := w.db.WithContext(ctx).Model(&models.StoredFile{}).Where("key = ?", a.URL).Count(&shared).Error; err != nil {
+			return err
+		}
+		if shared == 0 {
+			for _, k := range keys {
+				_ = w.store.Delete(ctx, k)
+			}
+		}
+	}
+	return nil
+}
+
+// render читает оригинал и сохраняет миниатюру и превью с учётом ориентации из EXIF;
+// возвращает их ключи.
+func (w *Worker) render(ctx context.Context, key string, orientation int) ([]string, error) {
+	obj, err := w.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// миниатюра строится из превью, а не из оригинала: так в разы быстрее для фото с телефона
+	// копии без EXIF, поэтому поворот применяется к пикселям (после уменьшения — быстрее)
+	medium := Orient(Resize(img, Medium), orientation)
+	thumb := Resize(medium, Thumbnail)
+
+	var keys []string
+	for _, c := range []struct {
+		v   Variant
+		img image.Image
+	}{{Thumbnail, thumb}, {Medium, medium}} {
+		out, err := Encode(c.img)
+		if err != nil {
+			return nil, err
+		}
+		k := Key(key, c.v)
+		if err := w.store.Put(ctx, k, bytes.NewReader(out), int64(len(out)), "image/jpeg"); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (w *Worker) setStatus(ctx context.Context, id uint, status string) error {
+	return w.db.WithContext(ctx).Model(&models.DefectAttachment{}).Where("id = ?", id).Update("preview_status", status).Error
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
+	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func RegisterAnalyticsRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, wf *workflow.Workflow) {
+	h := handlers.NewAnalyticsHandler(db, wf)
+
+	analytics := app.Group("/api/analytics", middleware.JWTMiddleware(tokens))
+
+	analytics.Get("/summary", h.GetSummary)
+	analytics.Get("/by-status", h.GetByStatus)
+	analytics.Get("/by-priority", h.GetByPriority)
+	analytics.Get("/by-building", h.GetByBuilding)
+	analytics.Get("/by-responsible", h.GetByResponsible)
+	analytics.Get("/sla/by-building", h.GetSLAByBuilding)
+	analytics.Get("/sla/by-responsible", h.GetSLAByResponsible)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
+	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func RegisterAttachmentExportRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, store storage.Storage, photos exif.Options) {
+	h := handlers.NewAttachmentExportHandler(db, store, photos)
+
+	// ZIP-архивы вложений, доступ как к списку вложений дефекта
+	app.Get("/api/defects/:id/attachments/export",
+		middleware.JWTMiddleware(tokens),
+		h.ExportDefectAttachments,
+	)
+
+	app.Get("/api/buildings/:id/attachments/export",
+		middleware.JWTMiddleware(tokens),
+		h.ExportBuildingAttachments,
+	)
+}
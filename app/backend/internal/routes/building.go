@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
 	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -8,28 +9,47 @@ import (
 )
 
 
-func RegisterBuildingRoutes(app *fiber.App, db *gorm.DB, jwtSecret string) {
+func RegisterBuildingRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager) {
 	h := handlers.NewBuildingHandler(db)
 
 	app.Post("/api/buildings", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		h.CreateBuilding,
 	)
 
 	app.Get("/api/buildings", h.GetBuildings)
 
+	// корзина регистрируется до /api/buildings/:id
+	app.Get("/api/buildings/trash", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer", "manager"),
+		h.GetDeletedBuildings,
+	)
+
 	app.Get("/api/buildings/:id", h.GetBuilding)
 
 	app.Patch("/api/buildings/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		h.UpdateBuilding,
 	)
 
 	app.Delete("/api/buildings/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		h.DeleteBuilding,
 	)
+
+	app.Post("/api/buildings/:id/restore", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer", "manager"),
+		h.RestoreBuilding,
+	)
+
+	app.Delete("/api/buildings/:id/purge", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer"),
+		h.PurgeBuilding,
+	)
 }
\ No newline at end of file
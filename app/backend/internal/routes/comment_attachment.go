@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
+	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
+	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func RegisterCommentAttachmentsRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, store storage.Storage, policy upload.Policy, signer *signedurl.Signer, photos exif.Options) {
+	h := handlers.NewCommentAttachmentHandler(db, store, policy, signer, photos)
+
+	// права (автор комментария, observer, manager) проверяются в обработчике
+	app.Post("/api/comments/:id/attachments",
+		middleware.JWTMiddleware(tokens),
+		h.UploadCommentAttachment,
+	)
+
+	app.Get("/api/comments/:id/attachments",
+		middleware.JWTMiddleware(tokens),
+		h.GetCommentAttachments,
+	)
+
+	app.Get("/api/comment-attachments/:id",
+		middleware.JWTMiddleware(tokens),
+		h.GetCommentAttachment,
+	)
+
+	// файл отдаётся по JWT или по подписанной ссылке из download_url
+	app.Get("/api/comment-attachments/:id/download",
+		middleware.SignedURLOrJWT(tokens, signer),
+		h.DownloadCommentAttachment,
+	)
+
+	app.Delete("/api/comment-attachments/:id",
+		middleware.JWTMiddleware(tokens),
+		h.DeleteCommentAttachment,
+	)
+}
@@ -1,29 +1,65 @@
 package routes
 
 import (
+	"time"
+
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
 	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-// Обновить комментарии нельзя (Сделано для того, чтобы всегда было видно историю)
-// TODO: добавить логгирование, а затем добавить возможность редактирования комментария. 
+// Редактировать комментарий может только автор в течение editWindow после создания;
+// каждая предыдущая версия сохраняется и доступна через /revisions.
+// Ответы задаются через parent_id, упоминания пользователя — через /api/users/:id/mentions.
 
-func RegisterCommentsRoutes(app *fiber.App, db *gorm.DB, jwtSecret string) {
-	h := handlers.NewCommentHandler(db)
+func RegisterCommentsRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, editWindow time.Duration, store storage.Storage) {
+	h := handlers.NewCommentHandler(db, editWindow, store)
 
 	app.Post("/api/comments", 
-		middleware.JWTMiddleware(jwtSecret), 
+		middleware.JWTMiddleware(tokens), 
 		h.CreateComment,
 	)
 	
 	app.Get("/api/comments", h.GetComments)
 
+	// корзина регистрируется до /api/comments/:id
+	app.Get("/api/comments/trash", 
+		middleware.JWTMiddleware(tokens), 
+		middleware.RequireRoles("observer"),
+		h.GetDeletedComments,
+	)
+
 	app.Get("/api/comments/:id", h.GetComment)
 
+	app.Patch("/api/comments/:id", 
+		middleware.JWTMiddleware(tokens), 
+		h.UpdateComment,
+	)
+
+	app.Get("/api/comments/:id/revisions", h.GetCommentRevisions)
+
 	app.Delete("/api/comments/:id", 
-		middleware.JWTMiddleware(jwtSecret), 
+		middleware.JWTMiddleware(tokens), 
 		h.DeleteComment,
 	)
+
+	app.Post("/api/comments/:id/restore", 
+		middleware.JWTMiddleware(tokens), 
+		middleware.RequireRoles("observer"),
+		h.RestoreComment,
+	)
+
+	app.Get("/api/users/:id/mentions", 
+		middleware.JWTMiddleware(tokens), 
+		h.GetUserMentions,
+	)
+
+	app.Delete("/api/comments/:id/purge", 
+		middleware.JWTMiddleware(tokens), 
+		middleware.RequireRoles("observer"),
+		h.PurgeComment,
+	)
 }
\ No newline at end of file
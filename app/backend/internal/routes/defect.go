@@ -1,32 +1,68 @@
 package routes
 
 import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
 	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-// TODO: create endpoint for updating
-
-func RegisterDefectRoutes(app *fiber.App, db *gorm.DB, jwtSecret string) {
-	dh := handlers.NewDefectHandler(db)			
+func RegisterDefectRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, wf *workflow.Workflow, store storage.Storage) {
+	dh := handlers.NewDefectHandler(db, wf, store)
 
 	app.Post("/api/defects", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		dh.CreateDefect,
 	)
 	app.Get("/api/defects", dh.GetDefects)
+
+	// корзина регистрируется до /api/defects/:id, иначе "trash" попадёт в :id
+	app.Get("/api/defects/trash", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer", "manager"),
+		dh.GetDeletedDefects,
+	)
+
 	app.Get("/api/defects/:id", dh.GetDefect)
 
 	app.Patch("/api/defects/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
+		dh.UpdateDefect,
+	)
+
+	app.Patch("/api/defects/:id/status", 
+		middleware.JWTMiddleware(tokens),
 		dh.UpdateStatus,
 	)
+
+	app.Get("/api/defects/:id/transitions", 
+		middleware.JWTMiddleware(tokens),
+		dh.GetTransitions,
+	)
+
+	app.Get("/api/defects/:id/history", 
+		middleware.JWTMiddleware(tokens),
+		dh.GetHistory,
+	)
 	
 	app.Delete("api/defects/:id",
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		dh.DeleteDefect,
 	)
+
+	app.Post("/api/defects/:id/restore",
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer", "manager"),
+		dh.RestoreDefect,
+	)
+
+	app.Delete("/api/defects/:id/purge",
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer"),
+		dh.PurgeDefect,
+	)
 }
\ No newline at end of file
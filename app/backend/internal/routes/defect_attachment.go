@@ -1,27 +1,56 @@
 package routes
 
 import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/exif"
 	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
 	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/preview"
+	"github.com/Quasar777/buildefect/app/backend/internal/signedurl"
+	"github.com/Quasar777/buildefect/app/backend/internal/storage"
+	"github.com/Quasar777/buildefect/app/backend/internal/upload"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func RegisterDefectAttachmentsRoutes(app *fiber.App, db *gorm.DB, jwtSecret string) {
-	h := handlers.NewDefectAttachmentHandler(db)
+func RegisterDefectAttachmentsRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, store storage.Storage, policy upload.Policy, signer *signedurl.Signer, previews *preview.Worker, photos exif.Options) {
+	h := handlers.NewDefectAttachmentHandler(db, store, policy, signer, previews, photos)
 
 	app.Post("/api/defects/:id/attachments", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		h.UploadDefectAttachment,
 	)
 
-	app.Get("/api/defects/:id/attachments", h.GetDefectAttachments)
+	app.Get("/api/defects/:id/attachments", 
+		middleware.JWTMiddleware(tokens),
+		h.GetDefectAttachments,
+	)
 	
-	app.Get("/api/attachments/:id", h.GetDefectAttachment)
+	app.Get("/api/attachments/:id", 
+		middleware.JWTMiddleware(tokens),
+		h.GetDefectAttachment,
+	)
+
+	// файл отдаётся по JWT или по подписанной ссылке из download_url
+	app.Get("/api/attachments/:id/download", 
+		middleware.SignedURLOrJWT(tokens, signer),
+		h.DownloadDefectAttachment,
+	)
+
+	// уменьшенные копии фото, доступ как к самому файлу
+	app.Get("/api/attachments/:id/thumbnail", 
+		middleware.SignedURLOrJWT(tokens, signer),
+		h.DownloadDefectAttachmentThumbnail,
+	)
+
+	app.Get("/api/attachments/:id/preview", 
+		middleware.SignedURLOrJWT(tokens, signer),
+		h.DownloadDefectAttachmentPreview,
+	)
 
 	app.Delete("/api/attachments/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer", "manager"),
 		h.DeleteDefectAttachment,
 	)
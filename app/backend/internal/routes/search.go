@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
+	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
+	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
+	"github.com/Quasar777/buildefect/app/backend/internal/workflow"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func RegisterSearchRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager, wf *workflow.Workflow) {
+	h := handlers.NewSearchHandler(db, wf)
+
+	app.Get("/api/search", middleware.JWTMiddleware(tokens), h.Search)
+}
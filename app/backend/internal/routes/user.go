@@ -1,54 +1,75 @@
 package routes
 
 import (
-	"time"
-
+	"github.com/Quasar777/buildefect/app/backend/internal/auth"
 	"github.com/Quasar777/buildefect/app/backend/internal/handlers"
 	"github.com/Quasar777/buildefect/app/backend/internal/middleware"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func RegisterUserRoutes(app *fiber.App, db *gorm.DB, jwtSecret string) {
-	uh := handlers.NewUserHandler(db)
-	ah := handlers.NewAuthHandler(db, jwtSecret, 24*time.Hour)
+func RegisterUserRoutes(app *fiber.App, db *gorm.DB, tokens *auth.Manager) {
+	uh := handlers.NewUserHandler(db, tokens)
+	ah := handlers.NewAuthHandler(db, tokens)
 
 	// auth
 	app.Post("/api/auth/register", ah.Register)
 	app.Post("/api/auth/login", ah.Login)
+	app.Post("/api/auth/refresh", ah.Refresh)
+	app.Post("/api/auth/logout", ah.Logout)
+	app.Get("/.well-known/jwks.json", ah.JWKS)
 
-	app.Get("/api/me", middleware.JWTMiddleware(jwtSecret), func(c *fiber.Ctx) error {
+	app.Get("/api/me", middleware.JWTMiddleware(tokens), func(c *fiber.Ctx) error {
 		return uh.GetUserByCtx(c) // implement helper in UserHandler to read c.Locals("user_id")
 	})
 
 	// user managing
 	app.Post("/api/users", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer"), 
 		uh.CreateUser,
 	)
 	
 	app.Get("/api/users", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		// middleware.RequireRoles("observer"), 
 		uh.GetUsers,
 	)
 
+	// корзина регистрируется до /api/users/:id
+	app.Get("/api/users/trash", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer"), 
+		uh.GetDeletedUsers,
+	)
+
 	app.Get("/api/users/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		// middleware.RequireRoles("observer"), 
 		uh.GetUser,
 	)
 
 	app.Patch("/api/users/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer"), 
 		uh.UpdateUser,
 	)
 
 	app.Delete("/api/users/:id", 
-		middleware.JWTMiddleware(jwtSecret),
+		middleware.JWTMiddleware(tokens),
 		middleware.RequireRoles("observer"), 
 		uh.DeleteUser,
 	)
+
+	app.Post("/api/users/:id/restore", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer"), 
+		uh.RestoreUser,
+	)
+
+	app.Delete("/api/users/:id/purge", 
+		middleware.JWTMiddleware(tokens),
+		middleware.RequireRoles("observer"), 
+		uh.PurgeUser,
+	)
 }
\ No newline at end of file
@@ -0,0 +1,64 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Короткоживущие ссылки на скачивание файлов. Подпись — HMAC-SHA256 от пути и времени
+// истечения, поэтому ссылку нельзя ни продлить, ни переставить на другой файл.
+// Такие ссылки работают без заголовка Authorization, например в <img src>.
+
+var (
+	ErrExpired = errors.New("signed url has expired")
+	ErrInvalid = errors.New("invalid url signature")
+)
+
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func New(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl}
+}
+
+// Sign возвращает path с параметрами expires и signature.
+func (s *Signer) Sign(path string) string {
+	expires := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.signature(path, expires))
+	return path + "?" + q.Encode()
+}
+
+// Verify проверяет подпись path и срок её действия.
+func (s *Signer) Verify(path, expires, signature string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalid
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(s.signature(path, expires))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalid
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	if time.Now().Unix() > exp {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(path + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package signedurl
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// signed подписывает path и возвращает параметры ссылки.
+func signed(t *testing.T, s *Signer, path string) (expires, signature string) {
+	t.Helper()
+	u, err := url.Parse(s.Sign(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != path {
+		t.Fatalf("signed path = %q, want %q", u.Path, path)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestVerify(t *testing.T) {
+	const path = "/api/attachments/7/download"
+	signer := New("secret", time.Minute)
+	expires, signature := signed(t, signer, path)
+	exp, _ := strconv.ParseInt(expires, 10, 64)
+
+	expiredExpires, expiredSignature := signed(t, New("secret", -time.Minute), path)
+
+	// меняем первый символ подписи на заведомо другой
+	tampered := "A" + signature[1:]
+	if tampered == signature {
+		tampered = "B" + signature[1:]
+	}
+
+	tests := []struct {
+		name                     string
+		signer                   *Signer
+		path, expires, signature string
+		want                     error
+	}{
+		{"действующая ссылка", signer, path, expires, signature, nil},
+		{"другой файл", signer, "/api/attachments/8/download", expires, signature, ErrInvalid},
+		{"другой путь к тому же файлу", signer, "/api/comment-attachments/7/download", expires, signature, ErrInvalid},
+		{"продлённый срок", signer, path, strconv.FormatInt(exp+3600, 10), signature, ErrInvalid},
+		{"срок не числом", signer, path, expires + "x", signature, ErrInvalid},
+		{"пустой срок", signer, path, "", signature, ErrInvalid},
+		{"подпись другим секретом", New("other", time.Minute), path, expires, signature, ErrInvalid},
+		{"подпись не base64", signer, path, expires, "!!!", ErrInvalid},
+		{"пустая подпись", signer, path, expires, "", ErrInvalid},
+		{"изменённая подпись", signer, path, expires, tampered, ErrInvalid},
+		{"истёкшая ссылка", signer, path, expiredExpires, expiredSignature, ErrExpired},
+		// продлить истёкшую ссылку нельзя: подпись покрывает срок
+		{"истёкшая ссылка с новым сроком", signer, path, expires, expiredSignature, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.signer.Verify(tt.path, tt.expires, tt.signature); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Хранилище файлов вложений. Ключ — относительный путь через "/", например
+// "defect_attachments/1759835216551583000_broken_wall.png"; в БД хранится именно он,
+// поэтому записи не зависят от того, где физически лежат файлы.
+
+// ErrNotFound возвращается, если объекта с таким ключом нет.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage общий интерфейс драйверов хранилища.
+type Storage interface {
+	// Put сохраняет содержимое r под ключом key, перезаписывая существующий объект.
+	// size — размер в байтах или -1, если неизвестен.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает объект на чтение. Вызывающий обязан закрыть Object.Body.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete удаляет объект. Отсутствие объекта ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+	// List вызывает fn для каждого объекта с ключом, начинающимся с prefix ("" — все объекты).
+	// Ошибка fn прерывает обход и возвращается из List.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
+
+// Object открытый на чтение объект хранилища.
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// ObjectInfo описание объекта при обходе хранилища.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Config параметры выбора и настройки драйвера.
+type Config struct {
+	// local или s3
+	Driver string
+
+	// local: корневой каталог
+	LocalRoot string
+
+	// s3: любой S3-совместимый сервис (AWS S3, MinIO, Yandex Object Storage…)
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// New создаёт драйвер, указанный в cfg.Driver.
+func New(ctx context.Context, cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.LocalRoot)
+	case "s3":
+		return NewS3(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q, use local or s3", cfg.Driver)
+	}
+}
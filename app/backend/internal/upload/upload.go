@@ -0,0 +1,176 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Проверка загружаемых файлов. Тип определяется по первым байтам файла (как
+// http.DetectContentType, плюс HEIC с телефонов), а не по заголовку или расширению
+// от клиента. Имя файла от клиента в ключ хранилища не попадает: ключ строится из хэша
+// содержимого, исходное имя после очистки хранится только в БД.
+
+// SniffLen столько первых байт файла нужно для определения типа.
+const SniffLen = 512
+
+// Типы, разрешённые по умолчанию: фото и PDF.
+var DefaultAllowedTypes = []string{"image/jpeg", "image/png", "image/webp", "image/heic", "application/pdf"}
+
+// Расширения для ключей хранилища по определённому типу.
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/heic":      ".heic",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// TooLargeError файл больше лимита для своего типа.
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("file is too large, max size is %s", FormatSize(e.Limit))
+}
+
+// Policy ограничения на загружаемые файлы.
+type Policy struct {
+	// максимальный размер изображения и любого другого файла, байт
+	MaxImageSize int64
+	MaxFileSize  int64
+	// разрешённые MIME-типы (определённые по содержимому)
+	AllowedTypes []string
+}
+
+// MaxSize наибольший из лимитов — под него настраивается лимит тела запроса.
+func (p Policy) MaxSize() int64 {
+	if p.MaxImageSize > p.MaxFileSize {
+		return p.MaxImageSize
+	}
+	return p.MaxFileSize
+}
+
+// Limit лимит размера для файла типа contentType.
+func (p Policy) Limit(contentType string) int64 {
+	if strings.HasPrefix(contentType, "image/") {
+		return p.MaxImageSize
+	}
+	return p.MaxFileSize
+}
+
+// Check проверяет тип и размер файла. Возвращает ErrUnsupportedType или *TooLargeError.
+func (p Policy) Check(contentType string, size int64) error {
+	allowed := false
+	for _, t := range p.AllowedTypes {
+		if t == contentType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ErrUnsupportedType
+	}
+	if limit := p.Limit(contentType); size > limit {
+		return &TooLargeError{Limit: limit}
+	}
+	return nil
+}
+
+// DetectType определяет MIME-тип по первым байтам файла (до SniffLen).
+func DetectType(head []byte) string {
+	// ISO BMFF: "....ftyp<brand>" — HEIC/HEIF с камер телефонов
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		switch string(head[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		}
+	}
+	t := http.DetectContentType(head)
+	// "text/plain; charset=utf-8" → "text/plain"
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
+
+// Key ключ хранилища "<prefix>/<hash><расширение по типу>": файлы хранятся по SHA-256
+// содержимого, одинаковые загрузки попадают в один объект.
+func Key(prefix, hash, contentType string) string {
+	return prefix + "/" + hash + extensions[contentType]
+}
+
+// ValidHash проверяет, что строка — SHA-256 в hex (64 символа в нижнем регистре).
+func ValidHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// maxFilenameLen ограничение длины сохраняемого имени, байт
+const maxFilenameLen = 200
+
+// SanitizeFilename оставляет от имени файла клиента только базовое имя без управляющих
+// символов, разделителей пути и кавычек. Пустой результат заменяется на "file".
+func SanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base("/" + name)
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r), r == '/', r == '"':
+			continue
+		default:
+			b.WriteRune(r)
+		}
+	}
+	clean := strings.Trim(strings.TrimSpace(b.String()), ".")
+	if clean == "" {
+		return "file"
+	}
+
+	// обрезаем по границе символа, сохраняя расширение
+	if len(clean) > maxFilenameLen {
+		ext := filepath.Ext(clean)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		base := clean[:maxFilenameLen-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		clean = base + ext
+	}
+	return clean
+}
+
+// FormatSize размер в байтах для сообщений об ошибках: "20 MB", "512 KB".
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
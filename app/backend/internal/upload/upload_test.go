@@ -0,0 +1,103 @@
+package upload
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// ftyp начало файла ISO BMFF с заданным основным брендом.
+func ftyp(brand string) []byte {
+	return append([]byte{0, 0, 0, 0x18, 'f', 't', 'y', 'p'}, []byte(brand+"\x00\x00\x00\x00mif1heic")...)
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"jpeg", []byte("\xFF\xD8\xFF\xE1\x00\x10Exif\x00\x00"), "image/jpeg"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"zip", []byte("PK\x03\x04\x14\x00"), "application/zip"},
+		{"heic", ftyp("heic"), "image/heic"},
+		{"heix", ftyp("heix"), "image/heic"},
+		{"mif1", ftyp("mif1"), "image/heic"},
+		{"msf1", ftyp("msf1"), "image/heic"},
+		{"mp4 не heic", ftyp("mp42"), "video/mp4"},
+		{"ftyp без бренда", []byte("\x00\x00\x00\x08ftyp"), "application/octet-stream"},
+		{"текст без charset", []byte("просто текст"), "text/plain"},
+		{"html", []byte("<!DOCTYPE html><html>"), "text/html"},
+		{"пусто", nil, "text/plain"},
+		{"двоичные данные", []byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+		// расширение или заявленный тип роли не играют — только байты
+		{"скрипт с расширением .jpg", []byte("#!/bin/sh\nrm -rf /\n"), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectType(tt.head); got != tt.want {
+				t.Errorf("DetectType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"обычное имя", "IMG_0001.HEIC", "IMG_0001.HEIC"},
+		{"кириллица и пробелы", "Трещина в стене.jpg", "Трещина в стене.jpg"},
+		{"путь unix", "/etc/passwd", "passwd"},
+		{"путь windows", `C:\Users\eng\Desktop\photo.jpg`, "photo.jpg"},
+		{"выход из каталога", "../../secret.pdf", "secret.pdf"},
+		{"только точки", "..", "file"},
+		{"ведущие точки", "...hidden.txt", "hidden.txt"},
+		{"завершающий слэш", "photos/", "photos"},
+		{"кавычки", `a"b".jpg`, "ab.jpg"},
+		{"управляющие символы", "a\r\nb\x00c\t.jpg", "abc.jpg"},
+		{"невалидный utf-8", "a\xffb.jpg", "ab.jpg"},
+		{"пробелы по краям", "  photo.jpg  ", "photo.jpg"},
+		{"пустое", "", "file"},
+		{"только мусор", "\x00\x01\"", "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.in); got != tt.want {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilenameLong(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantExt string
+	}{
+		{"ascii с расширением", strings.Repeat("a", 300) + ".jpg", ".jpg"},
+		// 'ж' — два байта: обрезка не должна резать символ пополам
+		{"кириллица", strings.Repeat("ж", 150) + ".pdf", ".pdf"},
+		{"длинное расширение отбрасывается", "a." + strings.Repeat("x", 300), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeFilename(tt.in)
+			if len(got) > maxFilenameLen {
+				t.Errorf("len = %d, want at most %d", len(got), maxFilenameLen)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("result %q is not valid UTF-8", got)
+			}
+			if tt.wantExt != "" && !strings.HasSuffix(got, tt.wantExt) {
+				t.Errorf("result %q lost extension %q", got, tt.wantExt)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Встроенная схема переходов, используется если DEFECT_WORKFLOW_FILE не задан
+//
+//go:embed default.json
+var defaultWorkflow []byte
+
+var (
+	ErrTransitionNotFound  = errors.New("transition not allowed from current status")
+	ErrTransitionForbidden = errors.New("role cannot perform this transition")
+	ErrCommentRequired     = errors.New("comment is required for this transition")
+)
+
+// Transition описывает один разрешённый переход статуса дефекта.
+type Transition struct {
+	Name           string   `json:"name"`
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	Roles          []string `json:"roles"`
+	RequireComment bool     `json:"require_comment"`
+}
+
+func (t Transition) AllowedFor(role string) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Статус новых дефектов, если в схеме не задан initial
+const defaultInitial = "new"
+
+// Завершённые статусы и статус выполненной работы, если в схеме не заданы final и closed
+var defaultFinal = []string{"closed", "cancelled"}
+
+const defaultClosed = "closed"
+
+// Workflow — конечный автомат статусов дефекта.
+type Workflow struct {
+	initial     string
+	final       []string
+	closed      string
+	transitions []Transition
+}
+
+type file struct {
+	Initial     string       `json:"initial"`
+	Final       []string     `json:"final"`
+	Closed      string       `json:"closed"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// Load читает схему переходов из JSON-файла. Пустой путь — встроенная схема по умолчанию.
+func Load(path string) (*Workflow, error) {
+	data := defaultWorkflow
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workflow file: %w", err)
+		}
+		data = b
+	}
+	return Parse(data)
+}
+
+// Parse разбирает и валидирует схему переходов.
+func Parse(data []byte) (*Workflow, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	if len(f.Transitions) == 0 {
+		return nil, errors.New("invalid workflow: no transitions defined")
+	}
+
+	if f.Initial == "" {
+		f.Initial = defaultInitial
+	}
+	if len(f.Final) == 0 {
+		f.Final = defaultFinal
+	}
+	if f.Closed == "" {
+		f.Closed = defaultClosed
+	}
+
+	seen := make(map[[2]string]struct{}, len(f.Transitions))
+	initialUsed := false
+	targets := make(map[string]bool)
+	for i, t := range f.Transitions {
+		if t.Name == "" || t.From == "" || t.To == "" {
+			return nil, fmt.Errorf("invalid workflow: transition #%d must have name, from and to", i)
+		}
+		if t.From == t.To {
+			return nil, fmt.Errorf("invalid workflow: transition %q goes from %q to itself", t.Name, t.From)
+		}
+		if len(t.Roles) == 0 {
+			return nil, fmt.Errorf("invalid workflow: transition %q has no roles", t.Name)
+		}
+		key := [2]string{t.From, t.To}
+		if _, dup := seen[key]; dup {
+			return nil, fmt.Errorf("invalid workflow: duplicate transition %s -> %s", t.From, t.To)
+		}
+		seen[key] = struct{}{}
+		if t.From == f.Initial {
+			initialUsed = true
+		}
+		targets[t.To] = true
+	}
+	// из начального статуса должен быть хотя бы один переход, иначе новые дефекты в нём застрянут
+	if !initialUsed {
+		return nil, fmt.Errorf("invalid workflow: no transitions from initial status %q", f.Initial)
+	}
+
+	// по завершённым статусам считается аналитика: статус, в который нельзя попасть,
+	// означает опечатку или схему без final, и метрики молча оказались бы нулевыми
+	closedFinal := false
+	for _, s := range f.Final {
+		if s == f.Initial {
+			return nil, fmt.Errorf("invalid workflow: initial status %q cannot be final", s)
+		}
+		if !targets[s] {
+			return nil, fmt.Errorf("invalid workflow: no transitions to final status %q", s)
+		}
+		if s == f.Closed {
+			closedFinal = true
+		}
+	}
+	if !closedFinal {
+		return nil, fmt.Errorf("invalid workflow: closed status %q is not final", f.Closed)
+	}
+
+	return &Workflow{initial: f.Initial, final: f.Final, closed: f.Closed, transitions: f.Transitions}, nil
+}
+
+// Initial статус, с которым создаются дефекты.
+func (w *Workflow) Initial() string {
+	return w.initial
+}
+
+// Final статусы, в которых дефект завершён: не считается открытым и не может быть просрочен.
+func (w *Workflow) Final() []string {
+	return w.final
+}
+
+// Closed завершённый статус, означающий выполненную работу; по нему считается время закрытия.
+func (w *Workflow) Closed() string {
+	return w.closed
+}
+
+// Find возвращает переход from -> to, если он описан в схеме.
+func (w *Workflow) Find(from, to string) (Transition, bool) {
+	for _, t := range w.transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// Check проверяет, может ли пользователь с ролью role перевести дефект из from в to.
+// comment — текст, приложенный к смене статуса (может быть пустым).
+func (w *Workflow) Check(from, to, role, comment string) (Transition, error) {
+	t, ok := w.Find(from, to)
+	if !ok {
+		return Transition{}, ErrTransitionNotFound
+	}
+	if !t.AllowedFor(role) {
+		return Transition{}, ErrTransitionForbidden
+	}
+	if t.RequireComment && comment == "" {
+		return Transition{}, ErrCommentRequired
+	}
+	return t, nil
+}
+
+// Available возвращает переходы из статуса from, доступные роли role.
+func (w *Workflow) Available(from, role string) []Transition {
+	res := make([]Transition, 0)
+	for _, t := range w.transitions {
+		if t.From == from && t.AllowedFor(role) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
@@ -0,0 +1,240 @@
+package workflow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadDefault(t *testing.T) {
+	w, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Initial(); got != "new" {
+		t.Errorf("initial = %q, want %q", got, "new")
+	}
+	if _, ok := w.Find("new", "in_progress"); !ok {
+		t.Error("default workflow has no new -> in_progress transition")
+	}
+}
+
+func TestParseFinal(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantFinal  string
+		wantClosed string
+	}{
+		{
+			name:       "по умолчанию closed и cancelled",
+			data:       testWorkflow,
+			wantFinal:  "closed,cancelled",
+			wantClosed: "closed",
+		},
+		{
+			name: "заданы в схеме",
+			data: `{"initial": "draft", "final": ["done", "rejected"], "closed": "done", "transitions": [
+				{"name": "submit", "from": "draft", "to": "open", "roles": ["engineer"]},
+				{"name": "finish", "from": "open", "to": "done", "roles": ["manager"]},
+				{"name": "reject", "from": "open", "to": "rejected", "roles": ["manager"]}
+			]}`,
+			wantFinal:  "done,rejected",
+			wantClosed: "done",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(w.Final(), ","); got != tt.wantFinal {
+				t.Errorf("final = %q, want %q", got, tt.wantFinal)
+			}
+			if got := w.Closed(); got != tt.wantClosed {
+				t.Errorf("closed = %q, want %q", got, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load("testdata/missing.json"); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestParseInitial(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "по умолчанию new",
+			data: `{"final": ["open"], "closed": "open", "transitions": [{"name": "start", "from": "new", "to": "open", "roles": ["engineer"]}]}`,
+			want: "new",
+		},
+		{
+			name: "задан в схеме",
+			data: `{"initial": "draft", "final": ["open"], "closed": "open", "transitions": [{"name": "submit", "from": "draft", "to": "open", "roles": ["engineer"]}]}`,
+			want: "draft",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Initial(); got != tt.want {
+				t.Errorf("initial = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // фрагмент текста ошибки
+	}{
+		{"не JSON", `{"transitions": [`, "invalid workflow"},
+		{"нет переходов", `{"transitions": []}`, "no transitions defined"},
+		{"нет имени", `{"transitions": [{"from": "new", "to": "open", "roles": ["engineer"]}]}`, "must have name, from and to"},
+		{"нет from", `{"transitions": [{"name": "start", "to": "open", "roles": ["engineer"]}]}`, "must have name, from and to"},
+		{"нет to", `{"transitions": [{"name": "start", "from": "new", "roles": ["engineer"]}]}`, "must have name, from and to"},
+		{"переход в себя", `{"transitions": [{"name": "noop", "from": "new", "to": "new", "roles": ["engineer"]}]}`, "to itself"},
+		{"нет ролей", `{"transitions": [{"name": "start", "from": "new", "to": "open", "roles": []}]}`, "has no roles"},
+		{
+			"дубликат",
+			`{"transitions": [
+				{"name": "start", "from": "new", "to": "open", "roles": ["engineer"]},
+				{"name": "begin", "from": "new", "to": "open", "roles": ["manager"]}
+			]}`,
+			"duplicate transition new -> open",
+		},
+		{
+			"нет переходов из initial",
+			`{"initial": "draft", "transitions": [{"name": "start", "from": "new", "to": "open", "roles": ["engineer"]}]}`,
+			`no transitions from initial status "draft"`,
+		},
+		{
+			"нет переходов из new по умолчанию",
+			`{"transitions": [{"name": "close", "from": "open", "to": "closed", "roles": ["engineer"]}]}`,
+			`no transitions from initial status "new"`,
+		},
+		{
+			"нет переходов в final по умолчанию",
+			`{"transitions": [{"name": "start", "from": "new", "to": "open", "roles": ["engineer"]}]}`,
+			`no transitions to final status "closed"`,
+		},
+		{
+			"опечатка в final",
+			`{"final": ["done", "rejected"], "closed": "done", "transitions": [
+				{"name": "finish", "from": "new", "to": "done", "roles": ["engineer"]},
+				{"name": "reject", "from": "new", "to": "rejectd", "roles": ["manager"]}
+			]}`,
+			`no transitions to final status "rejected"`,
+		},
+		{
+			"initial в final",
+			`{"final": ["new", "done"], "closed": "done", "transitions": [
+				{"name": "finish", "from": "new", "to": "done", "roles": ["engineer"]},
+				{"name": "reopen", "from": "done", "to": "new", "roles": ["manager"]}
+			]}`,
+			`initial status "new" cannot be final`,
+		},
+		{
+			"closed не из final",
+			`{"final": ["done"], "closed": "closed", "transitions": [
+				{"name": "finish", "from": "new", "to": "done", "roles": ["engineer"]},
+				{"name": "close", "from": "done", "to": "closed", "roles": ["manager"]}
+			]}`,
+			`closed status "closed" is not final`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+const testWorkflow = `{
+	"transitions": [
+		{"name": "start", "from": "new", "to": "in_progress", "roles": ["engineer", "manager"]},
+		{"name": "cancel", "from": "new", "to": "cancelled", "roles": ["manager"], "require_comment": true},
+		{"name": "finish", "from": "in_progress", "to": "closed", "roles": ["manager"]}
+	]
+}`
+
+func TestCheck(t *testing.T) {
+	w, err := Parse([]byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name              string
+		from, to, role    string
+		comment           string
+		wantErr           error
+		wantTransitionFor string
+	}{
+		{"разрешён", "new", "in_progress", "engineer", "", nil, "start"},
+		{"не описан", "new", "closed", "manager", "", ErrTransitionNotFound, ""},
+		{"обратный не описан", "in_progress", "new", "manager", "", ErrTransitionNotFound, ""},
+		{"чужая роль", "in_progress", "closed", "engineer", "", ErrTransitionForbidden, ""},
+		{"неизвестная роль", "new", "in_progress", "guest", "", ErrTransitionForbidden, ""},
+		{"без комментария", "new", "cancelled", "manager", "", ErrCommentRequired, ""},
+		{"с комментарием", "new", "cancelled", "manager", "дубль", nil, "cancel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := w.Check(tt.from, tt.to, tt.role, tt.comment)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tr.Name != tt.wantTransitionFor {
+				t.Errorf("transition = %q, want %q", tr.Name, tt.wantTransitionFor)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	w, err := Parse([]byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		from, role string
+		want       []string
+	}{
+		{"new", "manager", []string{"start", "cancel"}},
+		{"new", "engineer", []string{"start"}},
+		{"in_progress", "engineer", nil},
+		{"closed", "manager", nil},
+	}
+	for _, tt := range tests {
+		got := w.Available(tt.from, tt.role)
+		if got == nil {
+			t.Errorf("Available(%q, %q) = nil, want empty slice", tt.from, tt.role)
+		}
+		names := make([]string, 0, len(got))
+		for _, tr := range got {
+			names = append(names, tr.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Available(%q, %q) = %v, want %v", tt.from, tt.role, names, tt.want)
+		}
+	}
+}
@@ -4,13 +4,50 @@ import api from './axios';
 export interface Attachment {
   id: number;
   defect_id?: number;
-  url: string; // может быть "internal/uploads/..."
+  url: string; // ключ файла в хранилище, например "files/<sha256>.png"
+  hash?: string; // SHA-256 содержимого; по нему файл можно прикрепить ещё раз без загрузки
+  download_url: string; // подписанная ссылка на файл, живёт недолго
   filename?: string;
   content_type?: string;
   size?: number;
+  preview_status?: 'pending' | 'ready' | 'failed' | 'none';
+  thumbnail_url?: string; // миниатюра до 320 px, есть когда preview_status = 'ready'
+  preview_url?: string; // превью до 1280 px, есть когда preview_status = 'ready'
+  taken_at?: string | null; // время съёмки из EXIF
+  device?: string;
+  latitude?: number | null;
+  longitude?: number | null;
+  distance_from_building?: number | null; // м, если у фото и здания есть координаты
+  far_from_building?: boolean; // фото снято не на объекте
 }
 
+const API_ROOT = 'http://localhost:8080';
+
+// URL файла вложения для <img>: подписанная ссылка работает без заголовка Authorization
+export const attachmentFileUrl = (attachment: Attachment): string =>
+  `${API_ROOT}${attachment.download_url}`;
+
+// Миниатюра для карточек; пока она не готова (или это не фото) — оригинал
+export const attachmentThumbnailUrl = (attachment: Attachment): string =>
+  `${API_ROOT}${attachment.thumbnail_url || attachment.download_url}`;
+
+// Превью для страницы дефекта; пока оно не готово (или это не фото) — оригинал
+export const attachmentPreviewUrl = (attachment: Attachment): string =>
+  `${API_ROOT}${attachment.preview_url || attachment.download_url}`;
+
 export const getAttachmentsByDefect = async (defectId: number): Promise<Attachment[]> => {
   const { data } = await api.get(`/defects/${defectId}/attachments`);
   return data;
 };
+
+// ZIP со всеми файлами дефекта или здания. Эндпоинт требует токен, поэтому архив
+// скачивается через axios и сохраняется через временную ссылку.
+export const downloadAttachmentsZip = async (path: string, filename: string): Promise<void> => {
+  const { data } = await api.get(path, { responseType: 'blob' });
+  const url = URL.createObjectURL(data);
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = filename;
+  link.click();
+  URL.revokeObjectURL(url);
+};
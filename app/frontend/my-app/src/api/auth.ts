@@ -1,10 +1,12 @@
 // src/api/auth.ts
 import api from './axios';
 
-interface LoginResponse {
+export interface LoginResponse {
   access_token: string;
   token_type: string;
   expires_in: number;
+  refresh_token: string;
+  refresh_expires_in: number;
 }
 
 export const login = async (login: string, password: string): Promise<LoginResponse> => {
@@ -12,6 +14,11 @@ export const login = async (login: string, password: string): Promise<LoginRespo
   return data;
 };
 
+// Отзыв сеанса на сервере: access-токены этого сеанса сразу перестают работать
+export const logout = async (refreshToken: string) => {
+  await api.post('/auth/logout', { refresh_token: refreshToken });
+};
+
 export const register = async (login: string, password: string, name: string, lastname: string) => {
   const { data } = await api.post('/auth/register', { login, password, name, lastname });
   return data;
@@ -20,9 +20,33 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Обмен refresh-токена на новую пару. Refresh-токен одноразовый, поэтому
+// параллельные запросы, получившие 401, ждут одного общего обновления.
+let refreshing: Promise<string | null> | null = null;
+
+const refreshAccessToken = (): Promise<string | null> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('auth_refresh_token');
+    refreshing = (refreshToken
+      ? axios
+          .post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+          .then(({ data }) => {
+            localStorage.setItem('auth_token', data.access_token);
+            localStorage.setItem('auth_refresh_token', data.refresh_token);
+            return data.access_token as string;
+          })
+          .catch(() => null)
+      : Promise.resolve(null)
+    ).finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
 api.interceptors.response.use(
   (res) => res,
-  (error) => {
+  async (error) => {
     if (error && error.response) {
       const status = error.response.status;
       // Получаем путь запроса (например "/auth/login" или "/defects")
@@ -34,9 +58,20 @@ api.interceptors.response.use(
         const isAuthEndpoint =
           requestUrl.includes('/auth') || requestUrl.includes('/login') || requestUrl.includes('/register') || requestUrl.includes('/me');
 
+        // access-токен истёк или отозван — пробуем обновить его один раз и повторить запрос
+        if (!isAuthEndpoint && !error.config._retried) {
+          const token = await refreshAccessToken();
+          if (token) {
+            error.config._retried = true;
+            error.config.headers.Authorization = `Bearer ${token}`;
+            return api(error.config);
+          }
+        }
+
         if (!isAuthEndpoint) {
           // Очистим локал сторедж и перенаправим на логин только для защищённых запросов
           localStorage.removeItem('auth_token');
+          localStorage.removeItem('auth_refresh_token');
           localStorage.removeItem('auth_user');
           // Можно сделать Navigate через react-router, но interceptor не имеет доступа — используем location
           window.location.href = '/signin';
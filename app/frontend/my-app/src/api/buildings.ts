@@ -3,7 +3,7 @@ import api from './axios';
 
 export const getBuildings = async () => {
   const { data } = await api.get('/buildings');
-  return data;
+  return data.items;
 };
 
 export const getBuilding = async (id: number) => {
@@ -5,7 +5,7 @@ interface Defect {
   id: number;
   title: string;
   description: string;
-  status: 'new' | 'in_progress' | 'review' | 'canceled';
+  status: 'new' | 'in_progress' | 'review' | 'closed' | 'cancelled';
   deadline: string;
   responsible_person_id: number;
   responsible_person_name?: string;
@@ -23,7 +23,7 @@ const DefectCard: React.FC<DefectCardProps> = ({ defect, onClick }) => {
       new: 'Новая',
       in_progress: 'В работе',
       review: 'На проверке',
-      canceled: 'Отменена'
+      cancelled: 'Отменена'
     };
     return statusMap[status as keyof typeof statusMap] || status;
   };
@@ -33,7 +33,7 @@ const DefectCard: React.FC<DefectCardProps> = ({ defect, onClick }) => {
       new: 'defectCard__status--new',
       in_progress: 'defectCard__status--in-progress',
       review: 'defectCard__status--review',
-      canceled: 'defectCard__status--canceled'
+      cancelled: 'defectCard__status--cancelled'
     };
     return statusClassMap[status as keyof typeof statusClassMap] || '';
   };
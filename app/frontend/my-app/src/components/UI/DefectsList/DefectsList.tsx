@@ -1,16 +1,15 @@
 import React, { useState, useEffect } from 'react';
 import DefectCard from '../DefectCard/DefectCard';
 import './DefectsList.scss';
-import { getAttachmentsByDefect } from '../../../api/attachments';
+import { attachmentThumbnailUrl, getAttachmentsByDefect } from '../../../api/attachments';
 import api from '../../../api/axios';
 
-const API_ROOT = 'http://localhost:8080';
 
 interface Defect {
   id: number;
   title: string;
   description: string;
-  status: 'new' | 'in_progress' | 'review' | 'canceled';
+  status: 'new' | 'in_progress' | 'review' | 'closed' | 'cancelled';
   deadline: string;
   responsible_person_id: number;
   responsible_person_name?: string;
@@ -41,7 +40,7 @@ const DefectsList: React.FC<DefectsListProps> = ({ buildingId, onDefectClick })
     
     try {
       const res = await api.get(`/defects?building_id=${buildingId}`);
-      const data: Defect[] = res.data;
+      const data: Defect[] = res.data?.items;
 
       // Если нет дефектов — быстро выйти
       if (!Array.isArray(data) || data.length === 0) {
@@ -56,23 +55,7 @@ const DefectsList: React.FC<DefectsListProps> = ({ buildingId, onDefectClick })
           try {
             const atts = await getAttachmentsByDefect(d.id);
             if (Array.isArray(atts) && atts.length > 0) {
-              const first = atts[0];
-              // Преобразуем внутренний путь в публичный URL.
-              // Примеры входных значений: "internal/uploads/defect_attachments/xxx.png"
-              // Хотим: "http://localhost:8080/uploads/defect_attachments/xxx.png"
-              let publicPath = first.url;
-
-              // Если путь содержит "internal/", убираем его
-              if (publicPath.startsWith('internal/')) {
-                publicPath = publicPath.replace(/^internal\//, '');
-              }
-
-              // Убедимся, что путь начинается с uploads/
-              if (!publicPath.startsWith('/')) {
-                publicPath = `/${publicPath}`;
-              }
-
-              (d as Defect).image_url = `${API_ROOT}${publicPath}`;
+              (d as Defect).image_url = attachmentThumbnailUrl(atts[0]);
             } else {
               (d as Defect).image_url = undefined;
             }
@@ -48,6 +48,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
       const data = await authApi.login(login, password);
       const accessToken = data.access_token;
       localStorage.setItem('auth_token', accessToken);
+      localStorage.setItem('auth_refresh_token', data.refresh_token);
       setToken(accessToken);
 
       const meData = await authApi.me();
@@ -82,12 +83,17 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
   };
 
   const logout = () => {
+    const refreshToken = localStorage.getItem('auth_refresh_token');
     setUser(null);
     setToken(null);
     localStorage.removeItem('auth_token');
+    localStorage.removeItem('auth_refresh_token');
     localStorage.removeItem('auth_user');
-    // редирект на страницу входа
-    window.location.href = '/signin';
+    // отзываем сеанс на сервере (ошибка не мешает выйти локально), затем редирект на страницу входа
+    const revoke = refreshToken ? authApi.logout(refreshToken).catch(() => {}) : Promise.resolve();
+    revoke.finally(() => {
+      window.location.href = '/signin';
+    });
   };
 
   const value: AuthContextType = {
@@ -1,7 +1,7 @@
 import React, { useEffect, useMemo, useState } from 'react';
 import api from '../../api/axios';
 import './AnalyticsPage.scss';
-import { useParams, useNavigate } from 'react-router-dom';
+import { useNavigate } from 'react-router-dom';
 
 import { saveAs } from 'file-saver';
 import * as XLSX from 'xlsx';
@@ -20,6 +20,36 @@ import { Doughnut, Bar } from 'react-chartjs-2';
 
 ChartJS.register(CategoryScale, LinearScale, BarElement, ArcElement, Tooltip, Legend, Title);
 
+// Счётчики считает сервер (/analytics/*) по всем дефектам, а не по одной странице списка
+
+type Summary = {
+  total: number;
+  open: number;
+  overdue: number;
+  closed: number;
+  cancelled: number;
+  avg_close_hours: number | null;
+};
+
+type CountItem = {
+  key: string;
+  count: number;
+};
+
+type BuildingStat = {
+  building_id: number;
+  name: string;
+  total: number;
+  open: number;
+  overdue: number;
+};
+
+type ResponsibleStat = {
+  responsible: { id: number; name: string; lastname: string };
+  open: number;
+  overdue: number;
+};
+
 type Defect = {
   id: number;
   title?: string;
@@ -27,24 +57,19 @@ type Defect = {
   status?: string;
   deadline?: string | null;
   building_id?: number;
+  building?: { id: number; name: string };
   priority?: string;
   responsible_person_id?: number;
   created_at?: string;
   updated_at?: string;
 };
 
-type Building = {
-  id: number;
-  name: string;
-  address?: string;
-};
-
-const parseDeadline = (val?: string | null): Date | null => {
-  if (!val) return null;
-  const s = val.includes('T') ? val : val.replace(' ', 'T');
-  const d = new Date(s);
-  if (isNaN(d.getTime())) return null;
-  return d;
+const statusLabels: Record<string, string> = {
+  new: 'Новых',
+  in_progress: 'В работе',
+  review: 'На проверке',
+  closed: 'Закрытых',
+  cancelled: 'Отменённых',
 };
 
 // === EXPORT SECTION START ===
@@ -54,11 +79,27 @@ const csvEscape = (value: any) => {
   if (/[",\n\r]/.test(s)) return `"${s.replace(/"/g, '""')}"`;
   return s;
 };
+
+// Все дефекты для выгрузки: список отдаётся страницами, идём по next_cursor
+const fetchAllDefects = async (): Promise<Defect[]> => {
+  const all: Defect[] = [];
+  let cursor: string | null = null;
+  do {
+    const params: Record<string, string | number> = { limit: 100 };
+    if (cursor) params.cursor = cursor;
+    const res = await api.get<{ items: Defect[]; next_cursor: string | null }>('/defects', { params });
+    all.push(...(res.data?.items ?? []));
+    cursor = res.data?.next_cursor ?? null;
+  } while (cursor);
+  return all;
+};
 // === EXPORT SECTION END ===
 
 const AnalyticsPage: React.FC = () => {
-  const [defects, setDefects] = useState<Defect[]>([]);
-  const [buildings, setBuildings] = useState<Building[]>([]);
+  const [summary, setSummary] = useState<Summary | null>(null);
+  const [byStatus, setByStatus] = useState<CountItem[]>([]);
+  const [byBuilding, setByBuilding] = useState<BuildingStat[]>([]);
+  const [byResponsible, setByResponsible] = useState<ResponsibleStat[]>([]);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const navigate = useNavigate();
@@ -68,10 +109,16 @@ const AnalyticsPage: React.FC = () => {
       setLoading(true);
       setError(null);
       try {
-        const dRes = await api.get('/defects');
-        const bRes = await api.get('/buildings');
-        setDefects(Array.isArray(dRes.data) ? dRes.data : []);
-        setBuildings(Array.isArray(bRes.data) ? bRes.data : []);
+        const [sRes, stRes, bRes, rRes] = await Promise.all([
+          api.get('/analytics/summary'),
+          api.get('/analytics/by-status'),
+          api.get('/analytics/by-building'),
+          api.get('/analytics/by-responsible'),
+        ]);
+        setSummary(sRes.data);
+        setByStatus(Array.isArray(stRes.data) ? stRes.data : []);
+        setByBuilding(Array.isArray(bRes.data) ? bRes.data : []);
+        setByResponsible(Array.isArray(rRes.data) ? rRes.data : []);
       } catch (err: any) {
         setError(err?.response?.data?.error || 'Ошибка загрузки данных аналитики');
       } finally {
@@ -81,48 +128,17 @@ const AnalyticsPage: React.FC = () => {
     fetchAll();
   }, []);
 
-  const metrics = useMemo(() => {
-    const now = new Date();
-    const total = defects.length;
-
-    const countByStatus = defects.reduce<Record<string, number>>((acc, d) => {
-      const s = d.status || 'unknown';
-      acc[s] = (acc[s] || 0) + 1;
-      return acc;
-    }, {});
-
-    const closedCount = (countByStatus['closed'] || 0) + (countByStatus['canceled'] || 0);
-
-    const overdueCount = defects.reduce((acc, d) => {
-      const dl = parseDeadline(d.deadline);
-      if (!dl) return acc;
-      const isFinal = d.status === 'closed' || d.status === 'canceled';
-      if (!isFinal && dl < now) return acc + 1;
-      return acc;
-    }, 0);
-
-    const byBuildingMap = defects.reduce<Record<number, number>>((acc, d) => {
-      const bid = d.building_id || 0;
-      acc[bid] = (acc[bid] || 0) + 1;
-      return acc;
-    }, {});
-
-    const byBuilding = Object.entries(byBuildingMap).map(([bid, cnt]) => {
-      const id = Number(bid);
-      const b = buildings.find((x) => x.id === id);
-      return {
-        id,
-        name: b ? b.name : `Здание #${id}`,
-        count: cnt,
-      };
-    });
-
-    return { total, countByStatus, closedCount, overdueCount, byBuilding };
-  }, [defects, buildings]);
-
   // === EXPORT SECTION START ===
 
-  const exportFullDefectsCsv = () => {
+  const exportFullDefectsCsv = async () => {
+    let defects: Defect[];
+    try {
+      defects = await fetchAllDefects();
+    } catch (err: any) {
+      setError(err?.response?.data?.error || 'Ошибка выгрузки дефектов');
+      return;
+    }
+
     const headers = [
       'ID',
       'Здание',
@@ -139,7 +155,7 @@ const AnalyticsPage: React.FC = () => {
     const lines = [headers.join(',')];
 
     for (const d of defects) {
-      const bName = buildings.find((b) => b.id === d.building_id)?.name || `Здание #${d.building_id}`;
+      const bName = d.building?.name || `Здание #${d.building_id}`;
       const row = [
         d.id,
         bName,
@@ -162,8 +178,8 @@ const AnalyticsPage: React.FC = () => {
 
   const exportSummaryByBuildingXlsx = () => {
     const sheetData = [
-      ['ID здания', 'Название', 'Всего дефектов'],
-      ...metrics.byBuilding.map((b) => [b.id, b.name, b.count]),
+      ['ID здания', 'Название', 'Всего дефектов', 'Открыто', 'Просрочено'],
+      ...byBuilding.map((b) => [b.building_id, b.name, b.total, b.open, b.overdue]),
     ];
 
     const ws = XLSX.utils.aoa_to_sheet(sheetData);
@@ -173,21 +189,12 @@ const AnalyticsPage: React.FC = () => {
   };
 
   const exportStatusBreakdownCsv = () => {
-    const labels = {
-      new: 'Новых',
-      in_progress: 'В работе',
-      review: 'На проверке',
-      closed: 'Закрытых',
-      canceled: 'Отменённых',
-      unknown: 'Неизвестных',
-    };
-
     const headers = ['Статус', 'Количество'];
     const lines = [headers.join(',')];
 
-    for (const [status, count] of Object.entries(metrics.countByStatus)) {
-      const label = labels[status as keyof typeof labels] || status;
-      lines.push([csvEscape(label), csvEscape(count)].join(','));
+    for (const item of byStatus) {
+      const label = statusLabels[item.key] || item.key;
+      lines.push([csvEscape(label), csvEscape(item.count)].join(','));
     }
 
     const csvContent = lines.join('\r\n');
@@ -198,27 +205,16 @@ const AnalyticsPage: React.FC = () => {
   // === EXPORT SECTION END ===
 
   const doughnutData = useMemo(() => {
-    const labelMap: Record<string, string> = {
-      new: 'Новых',
-      in_progress: 'В работе',
-      review: 'На проверке',
-      closed: 'Закрытых',
-      canceled: 'Отменённых',
-      unknown: 'Неизвестных',
-    };
-
-    const labels = Object.keys(metrics.countByStatus).map((s) => labelMap[s] || s);
-    const data = Object.keys(metrics.countByStatus).map((s) => metrics.countByStatus[s]);
     return {
-      labels,
+      labels: byStatus.map((s) => statusLabels[s.key] || s.key),
       datasets: [
         {
-          data,
+          data: byStatus.map((s) => s.count),
           backgroundColor: ['#1890ff', '#fa8c16', '#52c41a', '#ff4d4f', '#bfbfbf'],
         },
       ],
     };
-  }, [metrics.countByStatus]);
+  }, [byStatus]);
 
   const doughnutOptions = {
     plugins: {
@@ -229,13 +225,11 @@ const AnalyticsPage: React.FC = () => {
   };
 
   const barData = useMemo(() => {
-    const labels = metrics.byBuilding.map((b) => b.name);
-    const data = metrics.byBuilding.map((b) => b.count);
     return {
-      labels,
-      datasets: [{ label: 'Дефекты', data, backgroundColor: '#1890ff' }],
+      labels: byBuilding.map((b) => b.name),
+      datasets: [{ label: 'Дефекты', data: byBuilding.map((b) => b.total), backgroundColor: '#1890ff' }],
     };
-  }, [metrics.byBuilding]);
+  }, [byBuilding]);
 
   const barOptions = {
     plugins: {
@@ -252,6 +246,31 @@ const AnalyticsPage: React.FC = () => {
     },
   };
 
+  const responsibleData = useMemo(() => {
+    return {
+      labels: byResponsible.map((r) => `${r.responsible.name} ${r.responsible.lastname}`.trim()),
+      datasets: [
+        { label: 'Открыто', data: byResponsible.map((r) => r.open), backgroundColor: '#1890ff' },
+        { label: 'Просрочено', data: byResponsible.map((r) => r.overdue), backgroundColor: '#ff4d4f' },
+      ],
+    };
+  }, [byResponsible]);
+
+  const responsibleOptions = {
+    plugins: {
+      legend: { position: 'bottom' as const },
+      title: { display: true, text: 'Открытые дефекты по ответственным' },
+    },
+    responsive: true,
+    maintainAspectRatio: false,
+    scales: {
+      y: {
+        beginAtZero: true,
+        ticks: { stepSize: 1, precision: 0 },
+      },
+    },
+  };
+
   return (
     <div className="analytics-page">
       <div className="analytics-page__back">
@@ -274,21 +293,27 @@ const AnalyticsPage: React.FC = () => {
         <>
           <div className="metrics-cards">
             <div className="metric-card">
-              <div className="metric-card__value">{metrics.total}</div>
+              <div className="metric-card__value">{summary?.total ?? 0}</div>
               <div className="metric-card__label">Всего дефектов</div>
             </div>
             <div className="metric-card">
-              <div className="metric-card__value">{metrics.countByStatus['new'] || 0}</div>
-              <div className="metric-card__label">Новых</div>
+              <div className="metric-card__value">{summary?.open ?? 0}</div>
+              <div className="metric-card__label">Открыто</div>
             </div>
             <div className="metric-card">
-              <div className="metric-card__value">{metrics.closedCount}</div>
+              <div className="metric-card__value">{(summary?.closed ?? 0) + (summary?.cancelled ?? 0)}</div>
               <div className="metric-card__label">Закрыто / Отменено</div>
             </div>
             <div className="metric-card">
-              <div className="metric-card__value">{metrics.overdueCount}</div>
+              <div className="metric-card__value">{summary?.overdue ?? 0}</div>
               <div className="metric-card__label">Просрочено</div>
             </div>
+            <div className="metric-card">
+              <div className="metric-card__value">
+                {summary?.avg_close_hours != null ? Math.round(summary.avg_close_hours * 10) / 10 : '—'}
+              </div>
+              <div className="metric-card__label">Среднее время закрытия, ч</div>
+            </div>
           </div>
 
           <div className="charts-row">
@@ -300,6 +325,12 @@ const AnalyticsPage: React.FC = () => {
               <Bar data={barData} options={barOptions} />
             </div>
           </div>
+
+          <div className="charts-row charts-row--single">
+            <div className="chart-card chart-card--bar">
+              <Bar data={responsibleData} options={responsibleOptions} />
+            </div>
+          </div>
         </>
       )}
     </div>
@@ -65,9 +65,10 @@ const CreateDefectPage: React.FC = () => {
   const fetchBuildings = async () => {
     try {
       const res = await api.get('/buildings');
-      setBuildings(res.data || []);
-      if (Array.isArray(res.data) && res.data.length > 0) {
-        setBuildingId((prev) => prev ?? res.data[0].id);
+      const items = res.data?.items || [];
+      setBuildings(items);
+      if (items.length > 0) {
+        setBuildingId((prev) => prev ?? items[0].id);
       }
     } catch (err) {
       console.error('Ошибка загрузки зданий:', err);
@@ -78,7 +79,7 @@ const CreateDefectPage: React.FC = () => {
   const fetchUsers = async () => {
     try {
       const res = await api.get('/users');
-      setUsers(res.data || []);
+      setUsers(res.data?.items || []);
     } catch (err) {
       // возможно нет доступа (403) — позволим вводить id вручную
       console.warn('Не удалось получить список пользователей (возможно нет прав):', err);
@@ -134,8 +135,6 @@ const CreateDefectPage: React.FC = () => {
         responsible_person_id: Number(responsibleId),
       };
       if (deadline) payload.deadline = deadline;
-      // status optional, backend defaults to "new" — но можно явно задать:
-      payload.status = 'new';
 
       // 1) Создаём дефект
       const res = await api.post('/defects', payload);
@@ -261,7 +260,7 @@ const CreateDefectPage: React.FC = () => {
 
         <div className="form-row">
           <label>Картинка (вложение)</label>
-          <input className='file-upload-input' type="file" accept="image/*" onChange={handleFileChange} />
+          <input className='file-upload-input' type="file" accept="image/jpeg,image/png,image/webp,image/heic,.heic" onChange={handleFileChange} />
           <div className="hint">Изображение загружается после создания дефекта (если выбрано)</div>
         </div>
 
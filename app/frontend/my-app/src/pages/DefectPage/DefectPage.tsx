@@ -2,6 +2,7 @@ import React, { useEffect, useState } from 'react';
 import { useParams, useNavigate } from 'react-router-dom';
 import { useAuth } from '../../context/AuthContext';
 import api from '../../api/axios';
+import { Attachment, attachmentPreviewUrl, downloadAttachmentsZip } from '../../api/attachments';
 import './DefectPage.scss';
 
 interface Defect {
@@ -10,7 +11,7 @@ interface Defect {
   building?: { id: number; name: string };
   title: string;
   description: string;
-  status: 'new' | 'in_progress' | 'review' | 'closed' | 'canceled';
+  status: 'new' | 'in_progress' | 'review' | 'closed' | 'cancelled';
   deadline: string;
   created_by?: { id: number; name: string; lastname: string };
   responsible_person_id: number;
@@ -18,11 +19,30 @@ interface Defect {
   updated_at: string;
 }
 
+// Переход статуса из GET /defects/:id/transitions (уже отфильтрован по роли пользователя)
+interface Transition {
+  name: string;
+  from: string;
+  to: Defect['status'];
+  require_comment: boolean;
+}
+
+// Подписи кнопок для переходов встроенной схемы; для остальных показывается имя перехода
+const transitionLabels: Record<string, string> = {
+  start: 'Приступить к работе',
+  send_to_review: 'Завершить задачу',
+  close: 'Подтвердить выполнение работы',
+  return: 'Вернуть в работу',
+  reset: 'Вернуть в новые',
+  reopen: 'Переоткрыть',
+  cancel: 'Отменить дефект',
+  restore: 'Восстановить',
+};
+
 interface Comment {
   id: number;
-  user_id: number;
-  user_name: string;
-  user_lastname: string;
+  created_by_person_id: number;
+  created_by: { id: number; name: string; lastname: string };
   text: string;
   created_at: string;
 }
@@ -34,7 +54,10 @@ const DefectPage: React.FC = () => {
 
   const [defect, setDefect] = useState<Defect | null>(null);
   const [attachmentUrl, setAttachmentUrl] = useState<string | null>(null);
+  const [attachment, setAttachment] = useState<Attachment | null>(null);
   const [comments, setComments] = useState<Comment[]>([]);
+  const [transitions, setTransitions] = useState<Transition[]>([]);
+  const [actionError, setActionError] = useState<string | null>(null);
   const [newComment, setNewComment] = useState('');
 
   const [loading, setLoading] = useState(true);
@@ -53,42 +76,24 @@ const DefectPage: React.FC = () => {
         const defectData: Defect = res.data;
         setDefect(defectData);
 
+        // Доступные текущему пользователю переходы статуса
+        const transitionsRes = await api.get(`/defects/${id}/transitions`);
+        setTransitions(transitionsRes.data);
+
         // Получаем фото дефекта
         const attachRes = await api.get(`/defects/${id}/attachments`);
         if (attachRes.data?.length > 0) {
-          const filePath = attachRes.data[0].url.replace(/^internal\//, '');
-          setAttachmentUrl(`http://localhost:8080/${filePath}`);
+          setAttachmentUrl(attachmentPreviewUrl(attachRes.data[0]));
+          setAttachment(attachRes.data[0]);
         }
 
-        // Получаем комментарии
-        const commentsRes = await api.get(`/comments?defect_id=${id}`);
-        const commentsData = commentsRes.data;
-
-        // Получаем уникальные user_id
-        const userIds = Array.from(new Set(commentsData.map((c: any) => c.created_by)));
-
-        // Подгружаем пользователей параллельно
-        const usersRes = await Promise.all(
-          userIds.map((uid) => api.get(`/users/${uid}`))
-        );
-        const usersMap = new Map<number, { name: string; lastname: string }>();
-        usersRes.forEach((r) => {
-          const u = r.data;
-          usersMap.set(u.id, { name: u.name, lastname: u.lastname });
-        });
-
-        // Обогащаем комментарии данными пользователя
-        const commentsWithUser = commentsData.map((c: any) => ({
-          ...c,
-          user_name: usersMap.get(c.created_by)?.name || 'Unknown',
-          user_lastname: usersMap.get(c.created_by)?.lastname || '',
-        }));
-
-        setComments(commentsWithUser);
+        // Получаем комментарии (автор приходит в created_by), старые сначала
+        const commentsRes = await api.get(`/comments?defect_id=${id}&sort=created_at`);
+        setComments(commentsRes.data.items);
 
         // Получаем всех пользователей для смены ответственного
         const usersListRes = await api.get('/users');
-        setAllUsers(usersListRes.data);
+        setAllUsers(usersListRes.data.items);
 
       } catch (err: any) {
         setError(err?.response?.data?.error || 'Ошибка загрузки дефекта');
@@ -101,13 +106,33 @@ const DefectPage: React.FC = () => {
   }, [id]);
 
 
-  const updateStatus = async (status: Defect['status']) => {
+  const changeStatus = async (transition: Transition) => {
     if (!defect) return;
+    let comment = '';
+    if (transition.require_comment) {
+      const text = window.prompt('Комментарий к смене статуса (обязателен)');
+      if (text === null) return;
+      comment = text.trim();
+      if (!comment) {
+        setActionError('Для этого перехода нужен комментарий');
+        return;
+      }
+    }
+    setActionError(null);
     try {
-      await api.patch(`/defects/${defect.id}`, { status });
-      setDefect({ ...defect, status, updated_at: new Date().toISOString() });
-    } catch (err) {
+      const res = await api.patch(`/defects/${defect.id}/status`, { status: transition.to, comment });
+      setDefect(res.data);
+
+      // переходы зависят от нового статуса, комментарий к смене статуса попадает в обсуждение
+      const transitionsRes = await api.get(`/defects/${defect.id}/transitions`);
+      setTransitions(transitionsRes.data);
+      if (comment) {
+        const commentsRes = await api.get(`/comments?defect_id=${defect.id}&sort=created_at`);
+        setComments(commentsRes.data.items);
+      }
+    } catch (err: any) {
       console.error(err);
+      setActionError(err?.response?.data?.error || 'Не удалось изменить статус');
     }
   };
 
@@ -130,14 +155,7 @@ const DefectPage: React.FC = () => {
       defect_id: defect.id
     });
 
-    // Обогащаем комментарий данными текущего пользователя
-    const newCommentWithUser: Comment = {
-      ...res.data,
-      user_name: user?.name || 'Unknown',
-      user_lastname: user?.lastname || ''
-    };
-
-    setComments([...comments, newCommentWithUser]);
+    setComments([...comments, res.data]);
     setNewComment('');
   } catch (err) {
     console.error(err);
@@ -145,6 +163,15 @@ const DefectPage: React.FC = () => {
 };
 
 
+  const downloadFiles = async () => {
+    if (!defect) return;
+    try {
+      await downloadAttachmentsZip(`/defects/${defect.id}/attachments/export`, `defect_${defect.id}_attachments.zip`);
+    } catch (err) {
+      console.error(err);
+    }
+  };
+
   const handleChangeResponsibleClick = () => {
     setEditingResponsible(true);
     setSelectedResponsibleId(defect?.responsible_person_id || null);
@@ -153,7 +180,6 @@ const DefectPage: React.FC = () => {
   if (loading) return <p>Загрузка...</p>;
   if (error || !defect) return <p>{error || 'Дефект не найден'}</p>;
 
-  const isEngineer = user?.role === 'engineer';
   const isManagerOrObserver = user?.role === 'manager' || user?.role === 'observer';
 
   return (
@@ -176,6 +202,14 @@ const DefectPage: React.FC = () => {
           <p><b>Статус:</b> {defect.status}</p>
           <p><b>Дедлайн:</b> {defect.deadline}</p>
           <p><b>Последнее</b> обновление: {defect.updated_at}</p>
+          {attachment?.taken_at && (
+            <p><b>Фото снято:</b> {new Date(attachment.taken_at).toLocaleString()}{attachment.device && `, ${attachment.device}`}</p>
+          )}
+          {attachment?.far_from_building && (
+            <p className="defect-page__photo-warning">
+              Фото снято в {Math.round((attachment.distance_from_building ?? 0) / 100) / 10} км от объекта
+            </p>
+          )}
 
           <p>
             <b>Ответственный:</b> {defect.responsible?.name} {defect.responsible?.lastname}
@@ -215,26 +249,20 @@ const DefectPage: React.FC = () => {
           <p><b>Создал:</b> {defect.created_by?.name} {defect.created_by?.lastname}</p>
 
           <div className="defect-page__actions">
-            {isEngineer && (
-              <>
-                {defect.status === 'new' && (
-                  <button onClick={() => updateStatus('in_progress')}>Приступить к работе</button>
-                )}
-                {defect.status === 'in_progress' && (
-                  <button onClick={() => updateStatus('review')}>Завершить задачу</button>
-                )}
-              </>
+            {attachment && (
+              <button onClick={downloadFiles}>Скачать все файлы (ZIP)</button>
             )}
+            {transitions.map(t => (
+              <button key={`${t.name}-${t.to}`} onClick={() => changeStatus(t)}>
+                {transitionLabels[t.name] || t.name}
+              </button>
+            ))}
 
             {isManagerOrObserver && (
-              <>
-                {defect.status !== 'closed' && (
-                  <button onClick={() => updateStatus('closed')}>Подтвердить выполнение работы</button>
-                )}
-                <button onClick={deleteDefect}>Удалить дефект</button>
-              </>
+              <button onClick={deleteDefect}>Удалить дефект</button>
             )}
           </div>
+          {actionError && <p className="defect-page__error">{actionError}</p>}
         </div>
       </div>
 
@@ -242,7 +270,7 @@ const DefectPage: React.FC = () => {
         <h3>Комментарии</h3>
         {comments.map((c) => (
           <div key={c.id} className="comment-card">
-            <p><b>{c.user_name} {c.user_lastname}</b></p>
+            <p><b>{c.created_by?.name} {c.created_by?.lastname}</b></p>
             <p>{new Date(c.created_at).toLocaleString()}</p>
             <p>{c.text}</p>
             {user?.role === 'observer' && (